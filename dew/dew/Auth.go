@@ -0,0 +1,112 @@
+package dew
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+)
+
+const (
+	//AuthUserKey 通过认证的用户名在 Context.Keys 中的键
+	AuthUserKey = "user"
+	//APIKeyKey 通过认证的 API key 在 Context.Keys 中的键
+	APIKeyKey = "api_key"
+)
+
+type (
+	//Accounts 用户名到密码的映射
+	Accounts map[string]string
+
+	//APIKeyConfig API key 认证配置
+	APIKeyConfig struct {
+		//Header 读取 key 的请求头, 默认 X-API-Key
+		Header string
+		//Query 头中没有时再读取的查询参数, 为空表示不从查询参数读取
+		Query string
+		//Keys 合法的 key
+		Keys []string
+		//Validate 自定义校验, 与 Keys 任一通过即可
+		Validate func(key string) bool
+	}
+
+	basicCredential struct {
+		header []byte
+		user   string
+	}
+)
+
+//secureContains 逐一比较所有候选项, 避免通过耗时推断出匹配位置
+func secureContains(candidates [][]byte, value []byte) int {
+	index := -1
+	for i, candidate := range candidates {
+		if subtle.ConstantTimeCompare(candidate, value) == 1 {
+			index = i
+		}
+	}
+	return index
+}
+
+func BasicAuth(accounts Accounts) HandlerFunction {
+	return BasicAuthForRealm(accounts, "")
+}
+
+//BasicAuthForRealm HTTP Basic 认证, 用户名保存在 AuthUserKey 下, realm 为空时使用 "Authorization Required"
+func BasicAuthForRealm(accounts Accounts, realm string) HandlerFunction {
+	if len(accounts) == 0 {
+		panic("dew: BasicAuth requires at least one account")
+	}
+	if realm == "" {
+		realm = "Authorization Required"
+	}
+	challenge := "Basic realm=" + strconv.Quote(realm)
+	headers := make([][]byte, 0, len(accounts))
+	credentials := make([]basicCredential, 0, len(accounts))
+	for user, password := range accounts {
+		if user == "" {
+			panic("dew: BasicAuth user can not be empty")
+		}
+		header := []byte("Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password)))
+		headers = append(headers, header)
+		credentials = append(credentials, basicCredential{header: header, user: user})
+	}
+
+	return NameHandler("dew/dew.BasicAuth", func(context *Context) {
+		index := secureContains(headers, []byte(context.Request.Header.Get("Authorization")))
+		if index < 0 {
+			context.SetHeader("WWW-Authenticate", challenge)
+			context.Fail(http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		context.Set(AuthUserKey, credentials[index].user)
+		context.Next()
+	})
+}
+
+//APIKey 从请求头或查询参数读取 key 进行认证, 通过后 key 保存在 APIKeyKey 下
+func APIKey(config APIKeyConfig) HandlerFunction {
+	if len(config.Keys) == 0 && nil == config.Validate {
+		panic("dew: APIKey requires Keys or Validate")
+	}
+	if config.Header == "" {
+		config.Header = "X-API-Key"
+	}
+	keys := make([][]byte, len(config.Keys))
+	for i, key := range config.Keys {
+		keys[i] = []byte(key)
+	}
+
+	return NameHandler("dew/dew.APIKey", func(context *Context) {
+		key := context.Request.Header.Get(config.Header)
+		if key == "" && config.Query != "" {
+			key = context.Query(config.Query)
+		}
+		if key == "" || (secureContains(keys, []byte(key)) < 0 &&
+			(nil == config.Validate || !config.Validate(key))) {
+			context.Fail(http.StatusUnauthorized, "invalid api key")
+			return
+		}
+		context.Set(APIKeyKey, key)
+		context.Next()
+	})
+}
@@ -0,0 +1,140 @@
+package dew
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func serveAuth(engine *Engine, target string, headers map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest("GET", target, nil)
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestBasicAuth(t *testing.T) {
+	engine := CreateEngine()
+	admin := engine.Group("/admin")
+	admin.Use(BasicAuthForRealm(Accounts{"foo": "bar"}, "admin"))
+	admin.GET("", func(context *Context) {
+		context.WriteString(http.StatusOK, context.GetString(AuthUserKey))
+	})
+
+	recorder := serveAuth(engine, "/admin", nil)
+	if recorder.Code != http.StatusUnauthorized || recorder.Header().Get("WWW-Authenticate") != `Basic realm="admin"` {
+		t.Fatalf("missing credentials should be rejected, got %d %v", recorder.Code, recorder.Header())
+	}
+	wrong := "Basic " + base64.StdEncoding.EncodeToString([]byte("foo:baz"))
+	if recorder := serveAuth(engine, "/admin", map[string]string{"Authorization": wrong}); recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong password should be rejected, got %d", recorder.Code)
+	}
+	right := "Basic " + base64.StdEncoding.EncodeToString([]byte("foo:bar"))
+	if recorder := serveAuth(engine, "/admin", map[string]string{"Authorization": right}); recorder.Body.String() != "foo" {
+		t.Fatalf("user should be stored, got %d %q", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	engine := CreateEngine()
+	engine.Use(APIKey(APIKeyConfig{Query: "key", Keys: []string{"secret"}}))
+	engine.GET("/", func(context *Context) {
+		context.WriteString(http.StatusOK, context.GetString(APIKeyKey))
+	})
+
+	if recorder := serveAuth(engine, "/", map[string]string{"X-API-Key": "secret"}); recorder.Body.String() != "secret" {
+		t.Fatalf("header key should pass, got %d", recorder.Code)
+	}
+	if recorder := serveAuth(engine, "/?key=secret", nil); recorder.Code != http.StatusOK {
+		t.Fatalf("query key should pass, got %d", recorder.Code)
+	}
+	if recorder := serveAuth(engine, "/?key=guess", nil); recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("unknown key should be rejected, got %d", recorder.Code)
+	}
+}
+
+func signToken(t *testing.T, algorithm string, claims JWTClaims, sign func([]byte) []byte) string {
+	header, _ := json.Marshal(H{"alg": algorithm, "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if nil != err {
+		t.Fatal(err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(signed)))
+}
+
+func TestJWT(t *testing.T) {
+	secret := []byte("secret")
+	hs256 := func(data []byte) []byte {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(data)
+		return mac.Sum(nil)
+	}
+	engine := CreateEngine()
+	engine.Use(JWT(JWTConfig{Secret: secret, Audience: "shop"}))
+	engine.GET("/me", func(context *Context) {
+		claims := context.MustGet(JWTClaimsKey).(JWTClaims)
+		context.WriteString(http.StatusOK, claims.String("sub"))
+	})
+
+	now := float64(time.Now().Unix())
+	cases := []struct {
+		name  string
+		token string
+		code  int
+	}{
+		{"valid", signToken(t, "HS256", JWTClaims{"sub": "alice", "aud": []string{"shop"}, "exp": now + 60}, hs256), http.StatusOK},
+		{"expired", signToken(t, "HS256", JWTClaims{"sub": "alice", "aud": "shop", "exp": now - 60}, hs256), http.StatusUnauthorized},
+		{"not before", signToken(t, "HS256", JWTClaims{"aud": "shop", "nbf": now + 60}, hs256), http.StatusUnauthorized},
+		{"audience", signToken(t, "HS256", JWTClaims{"aud": "blog"}, hs256), http.StatusUnauthorized},
+		{"none", signToken(t, "none", JWTClaims{"aud": "shop"}, func([]byte) []byte { return nil }), http.StatusUnauthorized},
+		{"tampered", signToken(t, "HS256", JWTClaims{"aud": "shop"}, func([]byte) []byte { return []byte("x") }), http.StatusUnauthorized},
+		{"malformed", "abc", http.StatusUnauthorized},
+		{"string exp", signToken(t, "HS256", JWTClaims{"aud": "shop", "exp": "never"}, hs256), http.StatusUnauthorized},
+		{"string nbf", signToken(t, "HS256", JWTClaims{"aud": "shop", "nbf": "now"}, hs256), http.StatusUnauthorized},
+		{"null iat", signToken(t, "HS256", JWTClaims{"aud": "shop", "iat": nil}, hs256), http.StatusUnauthorized},
+	}
+	for _, item := range cases {
+		recorder := serveAuth(engine, "/me", map[string]string{"Authorization": "Bearer " + item.token})
+		if recorder.Code != item.code {
+			t.Errorf("%s: expected %d, got %d %s", item.name, item.code, recorder.Code, recorder.Body.String())
+		}
+	}
+
+	config := JWTConfig{Secret: secret}
+	if _, err := config.ParseJWT(signToken(t, "HS256", JWTClaims{"exp": "never"}, hs256)); err != ErrTokenMalformed {
+		t.Fatalf("non-numeric exp should be malformed, got %v", err)
+	}
+}
+
+func TestJWTRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if nil != err {
+		t.Fatal(err)
+	}
+	rs256 := func(data []byte) []byte {
+		digest := sha256.Sum256(data)
+		signature, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		return signature
+	}
+	config := JWTConfig{PublicKey: &key.PublicKey}
+	if _, err := config.ParseJWT(signToken(t, "RS256", JWTClaims{"sub": "bob"}, rs256)); nil != err {
+		t.Fatal(err)
+	}
+	//只配置了公钥时, 不能用公钥当 HMAC 密钥伪造 HS256
+	forged := signToken(t, "HS256", JWTClaims{"sub": "bob"}, func([]byte) []byte { return nil })
+	if _, err := config.ParseJWT(forged); err != ErrTokenAlgorithm {
+		t.Fatalf("expected algorithm error, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package dew
+
+import (
+	"net/http"
+	"testing"
+)
+
+//nopWriter 丢弃输出, 避免把 httptest.ResponseRecorder 的分配计入结果
+type nopWriter struct {
+	header http.Header
+}
+
+func (this *nopWriter) Header() http.Header {
+	return this.header
+}
+
+func (this *nopWriter) Write(data []byte) (int, error) {
+	return len(data), nil
+}
+
+func (this *nopWriter) WriteHeader(code int) {}
+
+func newBenchmarkEngine() *Engine {
+	engine := CreateEngine()
+	engine.Use(func(context *Context) { context.Next() })
+	v1 := engine.Group("/v1")
+	v1.Use(func(context *Context) { context.Next() })
+	v1.GET("/users", func(context *Context) {})
+	v1.GET("/users/:id/books/:book", func(context *Context) {
+		context.Param("book")
+	})
+	v1.GET("/assets/*filepath", func(context *Context) {})
+	for _, path := range []string{"/a", "/b", "/c", "/d", "/e"} {
+		engine.GET(path+"/:name", func(context *Context) {})
+	}
+	return engine
+}
+
+func benchmarkServeHTTP(b *testing.B, method, path string) {
+	engine := newBenchmarkEngine()
+	request, _ := http.NewRequest(method, path, nil)
+	writer := &nopWriter{header: make(http.Header)}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		engine.ServeHTTP(writer, request)
+	}
+}
+
+func BenchmarkServeStatic(b *testing.B) {
+	benchmarkServeHTTP(b, "GET", "/v1/users")
+}
+
+func BenchmarkServeParams(b *testing.B) {
+	benchmarkServeHTTP(b, "GET", "/v1/users/42/books/7")
+}
+
+func BenchmarkServeCatchAll(b *testing.B) {
+	benchmarkServeHTTP(b, "GET", "/v1/assets/css/dew.css")
+}
+
+func BenchmarkServeParallel(b *testing.B) {
+	engine := newBenchmarkEngine()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		request, _ := http.NewRequest("GET", "/v1/users/42/books/7", nil)
+		writer := &nopWriter{header: make(http.Header)}
+		for pb.Next() {
+			engine.ServeHTTP(writer, request)
+		}
+	})
+}
+
+func BenchmarkGetRoute(b *testing.B) {
+	engine := newBenchmarkEngine()
+	params := make(Params, 0, engine.router.maxParams)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		params = params[:0]
+		engine.router.find("GET", "/v1/users/42/books/7", &params)
+	}
+}
+
+func TestServeHTTPAllocations(t *testing.T) {
+	engine := newBenchmarkEngine()
+	request, _ := http.NewRequest("GET", "/v1/users/42/books/7", nil)
+	writer := &nopWriter{header: make(http.Header)}
+	engine.ServeHTTP(writer, request)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		engine.ServeHTTP(writer, request)
+	})
+	if allocs != 0 {
+		t.Fatalf("ServeHTTP should not allocate on a matched route, got %v allocs", allocs)
+	}
+}
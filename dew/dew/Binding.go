@@ -0,0 +1,240 @@
+package dew
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//defaultMultipartMemory Engine.MaxMultipartMemory 的默认值
+const defaultMultipartMemory = 32 << 20
+
+var timeType = reflect.TypeOf(time.Time{})
+
+//Bind 根据请求方法和 Content-Type 选择解码方式: GET/HEAD 读取查询参数, JSON 读取请求体, 其余按表单处理
+func (this *Context) Bind(object interface{}) error {
+	if this.Method == http.MethodGet || this.Method == http.MethodHead {
+		return this.BindQuery(object)
+	}
+
+	contentType, _, _ := mime.ParseMediaType(this.Request.Header.Get("Content-Type"))
+	if contentType == "application/json" || strings.HasSuffix(contentType, "+json") {
+		return this.BindJSON(object)
+	}
+	return this.BindForm(object)
+}
+
+//BindJSON 把 JSON 请求体解码到 object 并校验
+func (this *Context) BindJSON(object interface{}) error {
+	if nil == this.Request.Body || http.NoBody == this.Request.Body {
+		return errors.New("dew: empty request body")
+	}
+	if err := json.NewDecoder(this.Request.Body).Decode(object); nil != err {
+		return err
+	}
+	return validate(object)
+}
+
+//BindQuery 按 form 标签把查询参数解码到 object 并校验
+func (this *Context) BindQuery(object interface{}) error {
+	if err := mapForm(object, this.Request.URL.Query(), "form"); nil != err {
+		return err
+	}
+	return validate(object)
+}
+
+//BindForm 按 form 标签把表单(含查询参数)解码到 object 并校验
+func (this *Context) BindForm(object interface{}) error {
+	contentType, _, _ := mime.ParseMediaType(this.Request.Header.Get("Content-Type"))
+	if contentType == "multipart/form-data" {
+		if err := this.Request.ParseMultipartForm(this.multipartMemory()); nil != err {
+			return err
+		}
+	} else if err := this.Request.ParseForm(); nil != err {
+		return err
+	}
+	if err := mapForm(object, this.Request.Form, "form"); nil != err {
+		return err
+	}
+	return validate(object)
+}
+
+//BindURI 按 uri 标签把路由参数解码到 object 并校验
+func (this *Context) BindURI(object interface{}) error {
+	values := make(map[string][]string, len(this.Params))
+	for _, param := range this.Params {
+		values[param.Key] = []string{param.Value}
+	}
+	if err := mapForm(object, values, "uri"); nil != err {
+		return err
+	}
+	return validate(object)
+}
+
+//mapForm 按 tag 把 values 写入 object 指向的结构体
+//标签格式为 `form:"name,default=value"`, 名称为 "-" 的字段被忽略, 缺省时使用字段名
+func mapForm(object interface{}, values map[string][]string, tag string) error {
+	value := reflect.ValueOf(object)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return errors.New("dew: binding target must be a non-nil pointer to struct")
+	}
+	return mapStruct(value.Elem(), values, tag)
+}
+
+func mapStruct(value reflect.Value, values map[string][]string, tag string) error {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if field.PkgPath != "" {
+			//未导出的字段不能赋值, 只展开嵌入的结构体值中导出的字段, 嵌入的指针无法初始化
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				if err := mapStruct(value.Field(i), values, tag); nil != err {
+					return err
+				}
+			}
+			continue
+		}
+
+		tagValue, tagged := field.Tag.Lookup(tag)
+		name, options := tagValue, ""
+		if index := strings.IndexByte(tagValue, ','); index >= 0 {
+			name, options = tagValue[:index], tagValue[index+1:]
+		}
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldValue := value.Field(i)
+		//未指定标签的嵌套结构体展开处理
+		if !tagged && isNestedStruct(field.Type) {
+			if fieldValue.Kind() == reflect.Ptr {
+				if fieldValue.IsNil() {
+					fieldValue.Set(reflect.New(field.Type.Elem()))
+				}
+				fieldValue = fieldValue.Elem()
+			}
+			if err := mapStruct(fieldValue, values, tag); nil != err {
+				return err
+			}
+			continue
+		}
+
+		inputs, ok := values[name]
+		if !ok || len(inputs) == 0 {
+			if !strings.HasPrefix(options, "default=") {
+				continue
+			}
+			inputs = []string{strings.TrimPrefix(options, "default=")}
+		}
+		if err := setField(fieldValue, field, inputs); nil != err {
+			return fmt.Errorf("dew: binding field '%s': %v", name, err)
+		}
+	}
+	return nil
+}
+
+func isNestedStruct(fieldType reflect.Type) bool {
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	return fieldType.Kind() == reflect.Struct && fieldType != timeType
+}
+
+func setField(value reflect.Value, field reflect.StructField, inputs []string) error {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+		return setField(value.Elem(), field, inputs)
+	case reflect.Slice:
+		slice := reflect.MakeSlice(value.Type(), len(inputs), len(inputs))
+		for i, input := range inputs {
+			if err := setValue(slice.Index(i), field, input); nil != err {
+				return err
+			}
+		}
+		value.Set(slice)
+		return nil
+	}
+	return setValue(value, field, inputs[0])
+}
+
+func setValue(value reflect.Value, field reflect.StructField, input string) error {
+	if value.Type() == timeType {
+		layout := field.Tag.Get("time_format")
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		if input == "" {
+			return nil
+		}
+		t, err := time.Parse(layout, input)
+		if nil != err {
+			return err
+		}
+		value.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(input)
+	case reflect.Bool:
+		if input == "" {
+			input = "false"
+		}
+		b, err := strconv.ParseBool(input)
+		if nil != err {
+			return err
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value.Type() == reflect.TypeOf(time.Duration(0)) {
+			d, err := time.ParseDuration(input)
+			if nil != err {
+				return err
+			}
+			value.SetInt(int64(d))
+			return nil
+		}
+		if input == "" {
+			input = "0"
+		}
+		n, err := strconv.ParseInt(input, 10, value.Type().Bits())
+		if nil != err {
+			return err
+		}
+		value.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if input == "" {
+			input = "0"
+		}
+		n, err := strconv.ParseUint(input, 10, value.Type().Bits())
+		if nil != err {
+			return err
+		}
+		value.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if input == "" {
+			input = "0"
+		}
+		n, err := strconv.ParseFloat(input, value.Type().Bits())
+		if nil != err {
+			return err
+		}
+		value.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return nil
+}
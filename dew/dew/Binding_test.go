@@ -0,0 +1,135 @@
+package dew
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindingAddress struct {
+	City string `form:"city" json:"city" binding:"required"`
+}
+
+type bindingUser struct {
+	bindingAddress
+	Name    string        `form:"name" json:"name" uri:"name" binding:"required,min=2,max=8"`
+	Age     int           `form:"age,default=18" json:"age" binding:"min=1,max=150"`
+	Email   string        `form:"email" json:"email" binding:"omitempty,email"`
+	Color   string        `form:"color" json:"color" binding:"omitempty,oneof=red green"`
+	Tags    []string      `form:"tag" json:"tags"`
+	Code    *string       `form:"code" json:"code" binding:"omitempty,len=4"`
+	Timeout time.Duration `form:"timeout" json:"timeout"`
+	Ignored string        `form:"-"`
+}
+
+func TestBindQuery(t *testing.T) {
+	request := httptest.NewRequest("GET", "/?name=geek&city=sz&tag=a&tag=b&code=1234&timeout=2s&Ignored=x", nil)
+	context := CreateContext(httptest.NewRecorder(), request)
+
+	var user bindingUser
+	if err := context.Bind(&user); nil != err {
+		t.Fatal(err)
+	}
+	code := "1234"
+	expected := bindingUser{
+		bindingAddress: bindingAddress{City: "sz"},
+		Name:           "geek",
+		Age:            18,
+		Tags:           []string{"a", "b"},
+		Code:           &code,
+		Timeout:        2 * time.Second,
+	}
+	if !reflect.DeepEqual(user, expected) {
+		t.Fatalf("unexpected binding %+v", user)
+	}
+}
+
+func TestBindFormAndURI(t *testing.T) {
+	request := httptest.NewRequest("POST", "/", strings.NewReader("city=sz&age=20"))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	context := CreateContext(httptest.NewRecorder(), request)
+	context.Params = Params{{Key: "name", Value: "geek"}}
+
+	var user bindingUser
+	if err := context.BindURI(&user); nil == err {
+		t.Fatal("city is required")
+	}
+	if err := context.BindForm(&user); nil != err {
+		t.Fatal(err)
+	}
+	if user.Name != "geek" || user.City != "sz" || user.Age != 20 {
+		t.Fatalf("unexpected binding %+v", user)
+	}
+}
+
+func TestBindValidation(t *testing.T) {
+	request := httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"g","age":200,"email":"bad","color":"blue"}`))
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	context := CreateContext(recorder, request)
+
+	var user bindingUser
+	err := context.Bind(&user)
+	validationErrors, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	tags := make([]string, 0)
+	for _, item := range validationErrors {
+		tags = append(tags, item.Field+":"+item.Tag)
+	}
+	expected := []string{"city:required", "name:min", "age:max", "email:email", "color:oneof"}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Fatalf("unexpected validation errors %v", tags)
+	}
+
+	context.Fail(http.StatusBadRequest, err)
+	if recorder.Code != http.StatusBadRequest || !strings.Contains(recorder.Body.String(), `{"field":"name","tag":"min","param":"2"}`) ||
+		strings.Contains(recorder.Body.String(), `"g"`) {
+		t.Fatalf("unexpected Fail output %s", recorder.Body.String())
+	}
+}
+
+type bindingBase struct {
+	Page int `form:"page"`
+}
+
+func TestBindUnexportedEmbedded(t *testing.T) {
+	request := httptest.NewRequest("GET", "/?page=2&name=x", nil)
+	context := CreateContext(httptest.NewRecorder(), request)
+
+	//嵌入的未导出指针无法赋值, 应被跳过而不是 panic
+	var withPointer struct {
+		*bindingBase
+		Name string `form:"name"`
+	}
+	if err := context.BindQuery(&withPointer); nil != err || withPointer.Name != "x" || nil != withPointer.bindingBase {
+		t.Fatalf("unexpected binding %+v %v", withPointer, err)
+	}
+
+	var withValue struct {
+		bindingBase
+		Name string `form:"name"`
+	}
+	if err := context.BindQuery(&withValue); nil != err || withValue.Page != 2 || withValue.Name != "x" {
+		t.Fatalf("unexpected binding %+v %v", withValue, err)
+	}
+}
+
+func TestRegisterValidation(t *testing.T) {
+	RegisterValidation("even", func(field reflect.Value, param string) bool {
+		return field.Int()%2 == 0
+	})
+	type number struct {
+		Value int `form:"value" binding:"even"`
+	}
+
+	context := CreateContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/?value=3", nil))
+	var n number
+	if err := context.BindQuery(&n); nil == err {
+		t.Fatal("3 should fail the even rule")
+	}
+}
@@ -0,0 +1,143 @@
+package dew
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//CORSConfig 跨域资源共享配置
+type CORSConfig struct {
+	//AllowOrigins 允许的来源, 支持 "*", 完整来源以及 "https://*.example.com" 形式的通配
+	AllowOrigins []string
+	//AllowOriginFunc 返回 true 时允许该来源, 与 AllowOrigins 任一匹配即可
+	AllowOriginFunc func(origin string) bool
+	//AllowMethods 预检请求允许的方法, 为空时使用路由中该路径实际注册的方法
+	AllowMethods []string
+	//AllowHeaders 预检请求允许的头, 为空时回显 Access-Control-Request-Headers
+	AllowHeaders []string
+	//ExposeHeaders 允许浏览器读取的响应头
+	ExposeHeaders []string
+	//AllowCredentials 是否允许携带 cookie 等凭证, 不能与 AllowOrigins 中的 "*" 同时使用
+	AllowCredentials bool
+	//MaxAge 预检结果的缓存时间
+	MaxAge time.Duration
+}
+
+//originMatcher 预先解析的来源规则
+type originMatcher struct {
+	any       bool
+	exact     map[string]bool
+	wildcards [][2]string
+	custom    func(origin string) bool
+}
+
+func newOriginMatcher(config CORSConfig) *originMatcher {
+	matcher := &originMatcher{
+		exact:  make(map[string]bool),
+		custom: config.AllowOriginFunc,
+	}
+	for _, origin := range config.AllowOrigins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		switch {
+		case origin == "*":
+			matcher.any = true
+		case strings.Count(origin, "*") == 1:
+			index := strings.IndexByte(origin, '*')
+			matcher.wildcards = append(matcher.wildcards, [2]string{origin[:index], origin[index+1:]})
+		case strings.Contains(origin, "*"):
+			panic("dew: only one '*' is allowed in CORS origin '" + origin + "'")
+		default:
+			matcher.exact[origin] = true
+		}
+	}
+	return matcher
+}
+
+func (this *originMatcher) match(origin string) bool {
+	lower := strings.ToLower(origin)
+	if this.any || this.exact[lower] {
+		return true
+	}
+	for _, wildcard := range this.wildcards {
+		if len(lower) > len(wildcard[0])+len(wildcard[1]) &&
+			strings.HasPrefix(lower, wildcard[0]) && strings.HasSuffix(lower, wildcard[1]) {
+			return true
+		}
+	}
+	return nil != this.custom && this.custom(origin)
+}
+
+//CORS 跨域中间件, 预检请求在这里直接以 204 结束, 不会进入路由处理器
+func CORS(config CORSConfig) HandlerFunction {
+	matcher := newOriginMatcher(config)
+	if matcher.any && config.AllowCredentials {
+		//允许任意来源携带凭证等于让所有网站都能以用户身份访问接口
+		panic("dew: CORS AllowOrigins \"*\" can not be used with AllowCredentials")
+	}
+	allowMethods := strings.Join(config.AllowMethods, ", ")
+	allowHeaders := strings.Join(config.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(config.ExposeHeaders, ", ")
+	maxAge := strconv.FormatInt(int64(config.MaxAge/time.Second), 10)
+
+	return NameHandler("dew/dew.CORS", func(context *Context) {
+		header := context.Writer.Header()
+		//响应随 Origin 变化, 没有 Origin 的请求也要声明, 否则缓存可能把无 CORS 头的响应返回给跨域请求
+		header.Add("Vary", "Origin")
+		origin := context.Request.Header.Get("Origin")
+		if origin == "" {
+			context.Next()
+			return
+		}
+
+		preflight := context.Method == http.MethodOptions && context.Request.Header.Get("Access-Control-Request-Method") != ""
+		if !matcher.match(origin) {
+			if preflight {
+				context.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			//普通请求照常处理, 没有 CORS 头浏览器自然会拦截
+			context.Next()
+			return
+		}
+
+		if matcher.any {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if config.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+			methods := allowMethods
+			if methods == "" {
+				methods = strings.Join(context.engine.router.allowed(context.Path), ", ")
+			}
+			if methods != "" {
+				header.Set("Access-Control-Allow-Methods", methods)
+			}
+			headers := allowHeaders
+			if headers == "" {
+				headers = context.Request.Header.Get("Access-Control-Request-Headers")
+			}
+			if headers != "" {
+				header.Set("Access-Control-Allow-Headers", headers)
+			}
+			if config.MaxAge > 0 {
+				header.Set("Access-Control-Max-Age", maxAge)
+			}
+			context.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if exposeHeaders != "" {
+			header.Set("Access-Control-Expose-Headers", exposeHeaders)
+		}
+		context.Next()
+	})
+}
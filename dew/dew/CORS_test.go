@@ -0,0 +1,95 @@
+package dew
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newCORSEngine(config CORSConfig) *Engine {
+	engine := CreateEngine()
+	engine.Use(CORS(config))
+	engine.GET("/books", func(context *Context) {
+		context.WriteString(http.StatusOK, "books")
+	})
+	engine.PUT("/books", func(context *Context) {})
+	return engine
+}
+
+func TestCORSPreflight(t *testing.T) {
+	engine := newCORSEngine(CORSConfig{
+		AllowOrigins:     []string{"https://*.example.com"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	})
+
+	request := httptest.NewRequest("OPTIONS", "/books", nil)
+	request.Header.Set("Origin", "https://shop.example.com")
+	request.Header.Set("Access-Control-Request-Method", "PUT")
+	request.Header.Set("Access-Control-Request-Headers", "Content-Type")
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+
+	header := recorder.Header()
+	if recorder.Code != http.StatusNoContent ||
+		header.Get("Access-Control-Allow-Origin") != "https://shop.example.com" ||
+		header.Get("Access-Control-Allow-Credentials") != "true" ||
+		header.Get("Access-Control-Allow-Methods") != "GET, HEAD, OPTIONS, PUT" ||
+		header.Get("Access-Control-Allow-Headers") != "Content-Type" ||
+		header.Get("Access-Control-Max-Age") != "3600" {
+		t.Fatalf("unexpected preflight response %d %v", recorder.Code, header)
+	}
+
+	request.Header.Set("Origin", "https://evil.com")
+	recorder = httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusForbidden {
+		t.Fatalf("disallowed origin should be rejected, got %d", recorder.Code)
+	}
+}
+
+func TestCORSSimpleRequest(t *testing.T) {
+	engine := newCORSEngine(CORSConfig{
+		AllowOrigins:    []string{"*"},
+		ExposeHeaders:   []string{"X-Total"},
+		AllowOriginFunc: func(origin string) bool { return strings.HasSuffix(origin, ".test") },
+	})
+
+	request := httptest.NewRequest("GET", "/books", nil)
+	request.Header.Set("Origin", "https://app.test")
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+	if recorder.Body.String() != "books" ||
+		recorder.Header().Get("Access-Control-Allow-Origin") != "*" ||
+		recorder.Header().Get("Access-Control-Expose-Headers") != "X-Total" ||
+		recorder.Header().Get("Vary") != "Origin" {
+		t.Fatalf("unexpected response %v", recorder.Header())
+	}
+
+	recorder = httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("GET", "/books", nil))
+	if recorder.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatal("requests without Origin should not get CORS headers")
+	}
+}
+
+func TestCORSWildcardWithCredentials(t *testing.T) {
+	defer func() {
+		if nil == recover() {
+			t.Fatal("\"*\" with AllowCredentials should panic")
+		}
+	}()
+	CORS(CORSConfig{AllowOrigins: []string{"*"}, AllowCredentials: true})
+}
+
+func TestCORSVaryWithoutOrigin(t *testing.T) {
+	engine := newCORSEngine(CORSConfig{AllowOrigins: []string{"https://app.example.com"}})
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("GET", "/books", nil))
+	if recorder.Body.String() != "books" || recorder.Header().Get("Vary") != "Origin" {
+		t.Fatalf("responses should vary on Origin even without one, got %v", recorder.Header())
+	}
+}
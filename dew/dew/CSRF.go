@@ -0,0 +1,190 @@
+package dew
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"html/template"
+	"net/http"
+	"time"
+)
+
+const (
+	//CSRFTokenKey 本次请求的 CSRF token 在 Context.Keys 中的键
+	CSRFTokenKey = "csrf_token"
+	//csrfSecretLength cookie 中保存的密钥长度
+	csrfSecretLength = 32
+)
+
+//CSRFConfig 双重提交 cookie 方式的 CSRF 防护配置
+type CSRFConfig struct {
+	//CookieName 保存密钥的 cookie, 默认 "_csrf"
+	CookieName string
+	//CookiePath 默认 "/"
+	CookiePath   string
+	CookieDomain string
+	//Secure 仅在 HTTPS 下发送 cookie
+	Secure bool
+	//SameSite 默认 Lax
+	SameSite http.SameSite
+	//MaxAge cookie 有效期, 默认 12 小时
+	MaxAge time.Duration
+	//HeaderName 读取 token 的请求头, 默认 X-CSRF-Token
+	HeaderName string
+	//FieldName 请求头中没有时读取的表单字段, 默认 "_csrf"
+	FieldName string
+	//ExemptPaths 不校验的路径前缀, 按路径段匹配, 通常用于使用 token 认证的 JSON API
+	ExemptPaths []string
+	//Exempt 返回 true 时不校验
+	Exempt func(context *Context) bool
+	//ErrorHandler 校验失败时的处理器, 默认返回 JSON 403
+	ErrorHandler HandlerFunction
+}
+
+//csrfSafeMethods 不修改状态的方法不需要校验
+var csrfSafeMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+//maskCSRFToken 每次用随机数异或密钥, 使页面中的 token 每次都不同, 防止 BREACH 之类的压缩侧信道攻击
+func maskCSRFToken(secret []byte) string {
+	token := make([]byte, 2*len(secret))
+	pad, masked := token[:len(secret)], token[len(secret):]
+	if _, err := rand.Read(pad); nil != err {
+		panic("dew: failed to generate csrf token: " + err.Error())
+	}
+	for i := range secret {
+		masked[i] = secret[i] ^ pad[i]
+	}
+	return base64.RawURLEncoding.EncodeToString(token)
+}
+
+func unmaskCSRFToken(token string) []byte {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if nil != err || len(data) != 2*csrfSecretLength {
+		return nil
+	}
+	pad, masked := data[:csrfSecretLength], data[csrfSecretLength:]
+	for i := range masked {
+		masked[i] ^= pad[i]
+	}
+	return masked
+}
+
+//CSRF 在 Keys 中签发 token, 并在不安全的方法上校验请求头或表单中的 token 是否与 cookie 中的密钥一致
+func CSRF(config CSRFConfig) HandlerFunction {
+	if config.CookieName == "" {
+		config.CookieName = "_csrf"
+	}
+	if config.CookiePath == "" {
+		config.CookiePath = "/"
+	}
+	if config.SameSite == 0 {
+		config.SameSite = http.SameSiteLaxMode
+	}
+	if config.MaxAge <= 0 {
+		config.MaxAge = 12 * time.Hour
+	}
+	if config.HeaderName == "" {
+		config.HeaderName = "X-CSRF-Token"
+	}
+	if config.FieldName == "" {
+		config.FieldName = "_csrf"
+	}
+	if nil == config.ErrorHandler {
+		config.ErrorHandler = func(context *Context) {
+			context.Fail(http.StatusForbidden, "invalid csrf token")
+		}
+	}
+
+	return NameHandler("dew/dew.CSRF", func(context *Context) {
+		for _, path := range config.ExemptPaths {
+			if hasPathPrefix(context.Path, path) {
+				context.Next()
+				return
+			}
+		}
+		if nil != config.Exempt && config.Exempt(context) {
+			context.Next()
+			return
+		}
+
+		var secret []byte
+		if cookie, err := context.Request.Cookie(config.CookieName); nil == err {
+			secret, _ = base64.RawURLEncoding.DecodeString(cookie.Value)
+		}
+		valid := len(secret) == csrfSecretLength
+		if !valid {
+			secret = make([]byte, csrfSecretLength)
+			if _, err := rand.Read(secret); nil != err {
+				panic("dew: failed to generate csrf secret: " + err.Error())
+			}
+			http.SetCookie(context.Writer, &http.Cookie{
+				Name:     config.CookieName,
+				Value:    base64.RawURLEncoding.EncodeToString(secret),
+				Path:     config.CookiePath,
+				Domain:   config.CookieDomain,
+				MaxAge:   int(config.MaxAge / time.Second),
+				Secure:   config.Secure,
+				HttpOnly: true,
+				SameSite: config.SameSite,
+			})
+		}
+		context.Writer.Header().Add("Vary", "Cookie")
+		context.Set(CSRFTokenKey, maskCSRFToken(secret))
+
+		if !csrfSafeMethods[context.Method] {
+			token := context.Request.Header.Get(config.HeaderName)
+			if token == "" {
+				token = context.Request.PostFormValue(config.FieldName)
+			}
+			//新生成的密钥不可能与请求中的 token 匹配
+			if !valid || subtle.ConstantTimeCompare(unmaskCSRFToken(token), secret) != 1 {
+				config.ErrorHandler(context)
+				context.Abort()
+				return
+			}
+		}
+		context.Next()
+	})
+}
+
+//CSRFToken 返回本次请求签发的 token, 没有使用 CSRF 中间件时为空串
+func CSRFToken(context *Context) string {
+	return context.GetString(CSRFTokenKey)
+}
+
+//csrfTokenOf 模板函数的参数可以是 *Context, 包含 CSRFTokenKey 的 H 或 token 本身
+func csrfTokenOf(value interface{}) string {
+	switch item := value.(type) {
+	case *Context:
+		return CSRFToken(item)
+	case H:
+		token, _ := item[CSRFTokenKey].(string)
+		return token
+	case map[string]interface{}:
+		token, _ := item[CSRFTokenKey].(string)
+		return token
+	case string:
+		return item
+	}
+	return ""
+}
+
+//CSRFFuncMap 模板函数 csrfToken 和 csrfField, fieldName 需与 CSRFConfig.FieldName 一致, 为空时为 "_csrf"
+//例如 WriteHTML(200, "form.tmpl", H{CSRFTokenKey: CSRFToken(context)}) 后在模板中使用 {{csrfField .}}
+func CSRFFuncMap(fieldName string) template.FuncMap {
+	if fieldName == "" {
+		fieldName = "_csrf"
+	}
+	return template.FuncMap{
+		"csrfToken": csrfTokenOf,
+		"csrfField": func(value interface{}) template.HTML {
+			return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(fieldName) +
+				`" value="` + template.HTMLEscapeString(csrfTokenOf(value)) + `">`)
+		},
+	}
+}
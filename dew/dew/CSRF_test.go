@@ -0,0 +1,88 @@
+package dew
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCSRF(t *testing.T) {
+	engine := CreateEngine()
+	engine.Use(CSRF(CSRFConfig{ExemptPaths: []string{"/api"}}))
+	engine.GET("/form", func(context *Context) {
+		context.WriteString(http.StatusOK, CSRFToken(context))
+	})
+	engine.POST("/form", func(context *Context) {
+		context.WriteString(http.StatusOK, "saved")
+	})
+	engine.POST("/api/orders", func(context *Context) {
+		context.WriteString(http.StatusOK, "created")
+	})
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("GET", "/form", nil))
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 || !cookies[0].HttpOnly {
+		t.Fatalf("expected a csrf cookie, got %v", cookies)
+	}
+	token := recorder.Body.String()
+
+	//同一个密钥每次签发的 token 都不同, 但都有效
+	recorder = httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "/form", nil)
+	request.AddCookie(cookies[0])
+	engine.ServeHTTP(recorder, request)
+	if recorder.Body.String() == token || len(recorder.Result().Cookies()) != 0 {
+		t.Fatal("token should be masked freshly and the cookie reused")
+	}
+	another := recorder.Body.String()
+
+	post := func(body, header string, withCookie bool) int {
+		request := httptest.NewRequest("POST", "/form", strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if header != "" {
+			request.Header.Set("X-CSRF-Token", header)
+		}
+		if withCookie {
+			request.AddCookie(cookies[0])
+		}
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+	if code := post("", "", true); code != http.StatusForbidden {
+		t.Fatalf("missing token should be rejected, got %d", code)
+	}
+	if code := post("", token, false); code != http.StatusForbidden {
+		t.Fatalf("missing cookie should be rejected, got %d", code)
+	}
+	if code := post("", token, true); code != http.StatusOK {
+		t.Fatalf("header token should pass, got %d", code)
+	}
+	if code := post("_csrf="+another, "", true); code != http.StatusOK {
+		t.Fatalf("form token should pass, got %d", code)
+	}
+	if code := post("", strings.Repeat("A", len(token)), true); code != http.StatusForbidden {
+		t.Fatalf("forged token should be rejected, got %d", code)
+	}
+
+	recorder = httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("POST", "/api/orders", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("exempt path should pass, got %d", recorder.Code)
+	}
+}
+
+func TestCSRFFuncMap(t *testing.T) {
+	tmpl := template.Must(template.New("form").Funcs(CSRFFuncMap("")).Parse(`<form>{{csrfField .}}</form>`))
+	var buffer bytes.Buffer
+	if err := tmpl.Execute(&buffer, H{CSRFTokenKey: "a-b_c"}); nil != err {
+		t.Fatal(err)
+	}
+	if buffer.String() != `<form><input type="hidden" name="_csrf" value="a-b_c"></form>` {
+		t.Fatalf("unexpected field %q", buffer.String())
+	}
+}
@@ -0,0 +1,288 @@
+package dew
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//CompressConfig 压缩中间件配置
+type CompressConfig struct {
+	//Level 压缩级别, 默认 gzip.DefaultCompression
+	Level int
+	//MinLength 小于该字节数的响应不压缩, 默认 1024
+	MinLength int
+	//ExcludedContentTypes 追加不压缩的类型, 以 '/' 结尾表示整个大类, 例如 "image/"
+	ExcludedContentTypes []string
+	//ExcludedPaths 不压缩的路径前缀, 按路径段匹配
+	ExcludedPaths []string
+}
+
+//defaultExcludedContentTypes 本身已压缩过的内容, 再压缩只会浪费 CPU
+var defaultExcludedContentTypes = []string{
+	"image/", "video/", "audio/", "font/woff", "font/woff2",
+	"application/zip", "application/gzip", "application/x-gzip", "application/x-bzip2",
+	"application/x-7z-compressed", "application/x-rar-compressed", "application/pdf",
+	"application/octet-stream", "text/event-stream",
+}
+
+type (
+	//compressor gzip.Writer 和 zlib.Writer 的公共方法
+	compressor interface {
+		io.WriteCloser
+		Flush() error
+		Reset(writer io.Writer)
+	}
+
+	//compressWriter 先缓冲 MinLength 字节, 再根据状态码和类型决定是否压缩
+	compressWriter struct {
+		ResponseWriter
+		config     *compressSettings
+		encoding   string
+		buffer     []byte
+		decided    bool
+		compressor compressor
+	}
+
+	compressSettings struct {
+		minLength int
+		excluded  []string
+		gzipPool  sync.Pool
+		zlibPool  sync.Pool
+	}
+)
+
+//Gzip 以指定级别压缩响应, 同时支持 deflate
+func Gzip(level int) HandlerFunction {
+	return Compress(CompressConfig{Level: level})
+}
+
+func Compress(config CompressConfig) HandlerFunction {
+	level := config.Level
+	if 0 == level {
+		level = gzip.DefaultCompression
+	}
+	if _, err := gzip.NewWriterLevel(ioutil.Discard, level); nil != err {
+		panic("dew: " + err.Error())
+	}
+	settings := &compressSettings{
+		minLength: config.MinLength,
+		excluded:  append(append([]string{}, defaultExcludedContentTypes...), config.ExcludedContentTypes...),
+	}
+	if settings.minLength <= 0 {
+		settings.minLength = 1024
+	}
+	settings.gzipPool.New = func() interface{} {
+		writer, _ := gzip.NewWriterLevel(ioutil.Discard, level)
+		return writer
+	}
+	settings.zlibPool.New = func() interface{} {
+		writer, _ := zlib.NewWriterLevel(ioutil.Discard, level)
+		return writer
+	}
+
+	return NameHandler("dew/dew.Compress", func(context *Context) {
+		request := context.Request
+		context.Writer.Header().Add("Vary", "Accept-Encoding")
+		//HEAD, Range 和协议升级的请求都不能改写响应体
+		if request.Method == http.MethodHead || request.Header.Get("Range") != "" ||
+			request.Header.Get("Upgrade") != "" {
+			context.Next()
+			return
+		}
+		for _, prefix := range config.ExcludedPaths {
+			if hasPathPrefix(context.Path, prefix) {
+				context.Next()
+				return
+			}
+		}
+		encoding := negotiateEncoding(request.Header.Get("Accept-Encoding"))
+		if encoding == "" {
+			context.Next()
+			return
+		}
+
+		original := context.Writer
+		writer := &compressWriter{
+			ResponseWriter: original,
+			config:         settings,
+			encoding:       encoding,
+		}
+		context.Writer = writer
+		defer func() {
+			writer.close()
+			context.Writer = original
+		}()
+		context.Next()
+	})
+}
+
+//negotiateEncoding 按 q 值在 gzip 和 deflate 之间选择, 都不接受时返回空串
+func negotiateEncoding(header string) string {
+	qualities := make(map[string]float64)
+	var encodings []string
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		encoding := strings.ToLower(strings.TrimSpace(fields[0]))
+		quality := 1.0
+		for _, field := range fields[1:] {
+			field = strings.TrimSpace(field)
+			if strings.HasPrefix(field, "q=") {
+				if value, err := strconv.ParseFloat(field[2:], 64); nil == err {
+					quality = value
+				}
+			}
+		}
+		if _, ok := qualities[encoding]; !ok {
+			encodings = append(encodings, encoding)
+		}
+		qualities[encoding] = quality
+	}
+	//"*" 只适用于没有显式列出的编码, 例如 "gzip;q=0, *" 不能选择 gzip
+	if quality, ok := qualities["*"]; ok {
+		for _, encoding := range []string{"gzip", "deflate"} {
+			if _, listed := qualities[encoding]; !listed {
+				qualities[encoding] = quality
+				encodings = append(encodings, encoding)
+			}
+		}
+	}
+
+	best, bestQuality := "", 0.0
+	for _, encoding := range encodings {
+		if (encoding == "gzip" || encoding == "deflate") && qualities[encoding] > bestQuality {
+			best, bestQuality = encoding, qualities[encoding]
+		}
+	}
+	return best
+}
+
+func (this *compressSettings) excludedType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if nil != err {
+		mediaType = strings.ToLower(contentType)
+	}
+	if mediaType == "image/svg+xml" {
+		return false
+	}
+	for _, excluded := range this.excluded {
+		if strings.HasSuffix(excluded, "/") && strings.HasPrefix(mediaType, excluded) || mediaType == excluded {
+			return true
+		}
+	}
+	return false
+}
+
+//decide 确定是否压缩并写出已缓冲的数据
+func (this *compressWriter) decide() error {
+	if this.decided {
+		return nil
+	}
+	this.decided = true
+
+	header := this.Header()
+	status := this.Status()
+	if "" == header.Get("Content-Type") && len(this.buffer) > 0 {
+		//先嗅探原始内容, 避免 net/http 之后对压缩数据进行嗅探
+		header.Set("Content-Type", http.DetectContentType(this.buffer))
+	}
+	compress := len(this.buffer) >= this.config.minLength &&
+		status >= http.StatusOK && status != http.StatusNoContent &&
+		status != http.StatusPartialContent && status != http.StatusNotModified &&
+		"" == header.Get("Content-Encoding") &&
+		!this.config.excludedType(header.Get("Content-Type"))
+
+	if compress {
+		header.Set("Content-Encoding", this.encoding)
+		header.Del("Content-Length")
+		if this.encoding == "gzip" {
+			this.compressor = this.config.gzipPool.Get().(*gzip.Writer)
+		} else {
+			this.compressor = this.config.zlibPool.Get().(*zlib.Writer)
+		}
+		this.compressor.Reset(this.ResponseWriter)
+	}
+
+	buffer := this.buffer
+	this.buffer = nil
+	if len(buffer) == 0 {
+		return nil
+	}
+	if nil != this.compressor {
+		_, err := this.compressor.Write(buffer)
+		return err
+	}
+	_, err := this.ResponseWriter.Write(buffer)
+	return err
+}
+
+func (this *compressWriter) Write(data []byte) (int, error) {
+	if !this.decided {
+		this.buffer = append(this.buffer, data...)
+		if len(this.buffer) < this.config.minLength {
+			return len(data), nil
+		}
+		if err := this.decide(); nil != err {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if nil != this.compressor {
+		return this.compressor.Write(data)
+	}
+	return this.ResponseWriter.Write(data)
+}
+
+func (this *compressWriter) WriteString(s string) (int, error) {
+	return this.Write([]byte(s))
+}
+
+//Written 已缓冲的数据也视为已写出, 防止之后再修改状态码
+func (this *compressWriter) Written() bool {
+	return len(this.buffer) > 0 || this.ResponseWriter.Written()
+}
+
+//WriteHeader 与 Written 保持一致, 有缓冲数据之后不再修改状态码
+func (this *compressWriter) WriteHeader(code int) {
+	if code > 0 && code != this.Status() && this.Written() {
+		log.Printf("[WARNING] headers were already written, wanted to override status code %d with %d", this.Status(), code)
+		return
+	}
+	this.ResponseWriter.WriteHeader(code)
+}
+
+func (this *compressWriter) WriteHeaderNow() {
+	this.decide()
+	this.ResponseWriter.WriteHeaderNow()
+}
+
+func (this *compressWriter) Flush() {
+	this.decide()
+	if nil != this.compressor {
+		this.compressor.Flush()
+	}
+	this.ResponseWriter.Flush()
+}
+
+//close 写出剩余数据并归还压缩器
+func (this *compressWriter) close() {
+	this.decide()
+	if nil == this.compressor {
+		return
+	}
+	this.compressor.Close()
+	switch item := this.compressor.(type) {
+	case *gzip.Writer:
+		this.config.gzipPool.Put(item)
+	case *zlib.Writer:
+		this.config.zlibPool.Put(item)
+	}
+	this.compressor = nil
+}
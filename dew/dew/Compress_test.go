@@ -0,0 +1,128 @@
+package dew
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+//newCompressEngine 返回的目录由调用方删除
+func newCompressEngine(t *testing.T) (*Engine, string) {
+	engine := CreateEngine()
+	engine.Use(Compress(CompressConfig{MinLength: 64}))
+	engine.GET("/large", func(context *Context) {
+		context.WriteJson(http.StatusOK, H{"data": strings.Repeat("dew ", 100)})
+	})
+	engine.GET("/small", func(context *Context) {
+		context.WriteString(http.StatusOK, "small")
+	})
+	engine.GET("/image", func(context *Context) {
+		context.WriteReader(http.StatusOK, -1, "image/png", strings.NewReader(strings.Repeat("x", 200)), nil)
+	})
+
+	root, err := ioutil.TempDir("", "dew-static")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "app.css"), []byte(strings.Repeat("body{}\n", 50)), 0644); nil != err {
+		os.RemoveAll(root)
+		t.Fatal(err)
+	}
+	engine.Static("/assets", root)
+	return engine, root
+}
+
+func compressRequest(engine *Engine, path, encoding string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest("GET", path, nil)
+	request.Header.Set("Accept-Encoding", encoding)
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestCompressGzip(t *testing.T) {
+	engine, root := newCompressEngine(t)
+	defer os.RemoveAll(root)
+	recorder := compressRequest(engine, "/large", "deflate;q=0.5, gzip")
+	if recorder.Header().Get("Content-Encoding") != "gzip" || recorder.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("large JSON should be gzipped, got %v", recorder.Header())
+	}
+	reader, err := gzip.NewReader(recorder.Body)
+	if nil != err {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(reader)
+	if !strings.HasPrefix(string(data), `{"data":"dew dew`) {
+		t.Fatalf("unexpected body %q", data)
+	}
+
+	recorder = compressRequest(engine, "/assets/app.css", "deflate")
+	if recorder.Header().Get("Content-Encoding") != "deflate" || recorder.Header().Get("Content-Length") != "" {
+		t.Fatalf("static file should be deflated, got %v", recorder.Header())
+	}
+	zreader, err := zlib.NewReader(recorder.Body)
+	if nil != err {
+		t.Fatal(err)
+	}
+	data, _ = ioutil.ReadAll(zreader)
+	if len(data) != 350 {
+		t.Fatalf("unexpected static body length %d", len(data))
+	}
+}
+
+func TestCompressSkipped(t *testing.T) {
+	engine, root := newCompressEngine(t)
+	defer os.RemoveAll(root)
+	for path, encoding := range map[string]string{
+		"/small": "gzip",
+		"/image": "gzip",
+		"/large": "br",
+	} {
+		recorder := compressRequest(engine, path, encoding)
+		if recorder.Header().Get("Content-Encoding") != "" {
+			t.Fatalf("%s with %s should not be compressed", path, encoding)
+		}
+	}
+	if recorder := compressRequest(engine, "/small", "gzip"); recorder.Body.String() != "small" {
+		t.Fatalf("small body should be passed through, got %q", recorder.Body.String())
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	cases := map[string]string{
+		"":                         "",
+		"gzip":                     "gzip",
+		"deflate":                  "deflate",
+		"deflate;q=0.5, gzip":      "gzip",
+		"gzip;q=0.2, deflate":      "deflate",
+		"br, identity":             "",
+		"*":                        "gzip",
+		"gzip;q=0, *":              "deflate",
+		"gzip;q=0, deflate;q=0, *": "",
+		"*;q=0":                    "",
+	}
+	for header, expected := range cases {
+		if encoding := negotiateEncoding(header); encoding != expected {
+			t.Errorf("%q: expected %q, got %q", header, expected, encoding)
+		}
+	}
+}
+
+func TestCompressWriteHeaderAfterBuffering(t *testing.T) {
+	engine := CreateEngine()
+	engine.Use(Compress(CompressConfig{MinLength: 64}))
+	engine.GET("/", func(context *Context) {
+		context.Writer.WriteString("buffered")
+		//已缓冲数据时 Written 为 true, 状态码也不能再被修改
+		context.Writer.WriteHeader(http.StatusTeapot)
+	})
+	if recorder := compressRequest(engine, "/", "gzip"); recorder.Code != http.StatusOK || recorder.Body.String() != "buffered" {
+		t.Fatalf("status should not change after buffering, got %d %q", recorder.Code, recorder.Body.String())
+	}
+}
@@ -2,23 +2,39 @@ package dew
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 )
 
+//abortIndex Abort 之后 index 的取值, 大于任何处理链的长度
+const abortIndex int = math.MaxInt32 / 2
+
 type (
 	H map[string]interface{}
 
+	//Context 由 Engine 池化复用, 处理器返回后不应再持有它
 	Context struct {
 		//来源
-		Writer  http.ResponseWriter
-		Request *http.Request
+		writermem responseWriter
+		Writer    ResponseWriter
+		Request   *http.Request
 		//请求
 		Path   string
 		Method string
-		Params map[string]string
+		Params Params
 		//响应
 		Code int
+		//处理过程中收集的错误
+		Errors ErrorList
+		//请求范围内的键值对, 用于在中间件和处理器之间传递数据
+		Keys  map[string]interface{}
+		mutex sync.RWMutex
 		//中间件
 		handlers []HandlerFunction
 		index    int
@@ -27,13 +43,24 @@ type (
 )
 
 func CreateContext(writer http.ResponseWriter, request *http.Request) *Context {
-	return &Context{
-		Path:    request.URL.Path,
-		Method:  request.Method,
-		Writer:  writer,
-		Request: request,
-		index:   -1,
-	}
+	context := &Context{}
+	context.reset(writer, request)
+	return context
+}
+
+//reset 复用前清理上一次请求留下的状态
+func (this *Context) reset(writer http.ResponseWriter, request *http.Request) {
+	this.writermem.reset(writer)
+	this.Writer = &this.writermem
+	this.Request = request
+	this.Path = request.URL.Path
+	this.Method = request.Method
+	this.Params = this.Params[:0]
+	this.Code = 0
+	this.Errors = this.Errors[:0]
+	this.Keys = nil
+	this.handlers = nil
+	this.index = -1
 }
 
 func (this *Context) Next() {
@@ -44,12 +71,242 @@ func (this *Context) Next() {
 	}
 }
 
-func (this *Context) Fail(code int, err string) {
-	this.index = len(this.handlers)
-	this.WriteJson(code, H{
-		"code":    code,
-		"message": err,
-	})
+//Abort 阻止执行后续处理器, 已经在执行的处理器不受影响
+func (this *Context) Abort() {
+	this.index = abortIndex
+}
+
+func (this *Context) IsAborted() bool {
+	return this.index >= abortIndex
+}
+
+//AbortWithStatus 终止处理链并立即写出状态码
+func (this *Context) AbortWithStatus(code int) {
+	this.SetCode(code)
+	this.Writer.WriteHeaderNow()
+	this.Abort()
+}
+
+//AbortWithError 终止处理链, 写出状态码并记录错误
+func (this *Context) AbortWithError(code int, err error) *Error {
+	this.AbortWithStatus(code)
+	return this.Error(err)
+}
+
+//Error 记录一个错误, 供之后的中间件在 Next() 返回后检查
+func (this *Context) Error(err error) *Error {
+	if nil == err {
+		panic("dew: err is nil")
+	}
+	var parsed *Error
+	if !errors.As(err, &parsed) {
+		parsed = &Error{
+			Err:  err,
+			Type: ErrorTypePrivate,
+		}
+	}
+	this.Errors = append(this.Errors, parsed)
+	return parsed
+}
+
+//Fail 终止后续处理器并输出 JSON 错误, err 可以是字符串, error 或 ValidationErrors
+func (this *Context) Fail(code int, err interface{}) {
+	this.Abort()
+	body := H{"code": code}
+	var validationErrors ValidationErrors
+	switch value := err.(type) {
+	case error:
+		body["message"] = value.Error()
+		if errors.As(value, &validationErrors) {
+			body["message"] = "validation failed"
+			body["errors"] = validationErrors
+		}
+	default:
+		body["message"] = fmt.Sprint(value)
+	}
+	this.WriteJson(code, body)
+}
+
+//ErrCopiedContext 副本只能读取请求数据, 写响应时返回该错误
+var ErrCopiedContext = errors.New("dew: can not write the response from a copied Context")
+
+//copiedWriter 副本使用的 ResponseWriter, 丢弃所有写入并返回 ErrCopiedContext
+type copiedWriter struct {
+	header http.Header
+}
+
+func (this *copiedWriter) Header() http.Header {
+	return this.header
+}
+
+func (this *copiedWriter) Write([]byte) (int, error) {
+	return 0, ErrCopiedContext
+}
+
+func (this *copiedWriter) WriteHeader(int) {}
+
+//Copy 返回可以在处理器返回后继续使用的副本, 例如交给 goroutine
+//副本是只读的, 通过它写响应不会影响原请求, Write 返回 ErrCopiedContext
+func (this *Context) Copy() *Context {
+	copied := &Context{
+		Request: this.Request,
+		Path:    this.Path,
+		Method:  this.Method,
+		Code:    this.Code,
+		index:   abortIndex,
+		engine:  this.engine,
+	}
+	copied.writermem.reset(&copiedWriter{header: make(http.Header)})
+	copied.Writer = &copied.writermem
+	copied.Params = make(Params, len(this.Params))
+	copy(copied.Params, this.Params)
+
+	this.mutex.RLock()
+	copied.Keys = make(map[string]interface{}, len(this.Keys))
+	for key, value := range this.Keys {
+		copied.Keys[key] = value
+	}
+	this.mutex.RUnlock()
+	return copied
+}
+
+//Set 保存请求范围内的数据
+func (this *Context) Set(key string, value interface{}) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if nil == this.Keys {
+		this.Keys = make(map[string]interface{})
+	}
+	this.Keys[key] = value
+}
+
+//Get 读取 Set 保存的数据
+func (this *Context) Get(key string) (value interface{}, exists bool) {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	value, exists = this.Keys[key]
+	return
+}
+
+//MustGet 读取 Set 保存的数据, 不存在时 panic
+func (this *Context) MustGet(key string) interface{} {
+	if value, exists := this.Get(key); exists {
+		return value
+	}
+	panic("dew: key \"" + key + "\" does not exist")
+}
+
+func (this *Context) GetString(key string) (s string) {
+	if value, ok := this.Get(key); ok && nil != value {
+		s, _ = value.(string)
+	}
+	return
+}
+
+func (this *Context) GetBool(key string) (b bool) {
+	if value, ok := this.Get(key); ok && nil != value {
+		b, _ = value.(bool)
+	}
+	return
+}
+
+func (this *Context) GetInt(key string) (i int) {
+	if value, ok := this.Get(key); ok && nil != value {
+		i, _ = value.(int)
+	}
+	return
+}
+
+func (this *Context) GetInt64(key string) (i int64) {
+	if value, ok := this.Get(key); ok && nil != value {
+		i, _ = value.(int64)
+	}
+	return
+}
+
+func (this *Context) GetUint(key string) (i uint) {
+	if value, ok := this.Get(key); ok && nil != value {
+		i, _ = value.(uint)
+	}
+	return
+}
+
+func (this *Context) GetFloat64(key string) (f float64) {
+	if value, ok := this.Get(key); ok && nil != value {
+		f, _ = value.(float64)
+	}
+	return
+}
+
+func (this *Context) GetTime(key string) (t time.Time) {
+	if value, ok := this.Get(key); ok && nil != value {
+		t, _ = value.(time.Time)
+	}
+	return
+}
+
+func (this *Context) GetDuration(key string) (d time.Duration) {
+	if value, ok := this.Get(key); ok && nil != value {
+		d, _ = value.(time.Duration)
+	}
+	return
+}
+
+func (this *Context) GetStringSlice(key string) (ss []string) {
+	if value, ok := this.Get(key); ok && nil != value {
+		ss, _ = value.([]string)
+	}
+	return
+}
+
+func (this *Context) GetStringMap(key string) (sm map[string]interface{}) {
+	if value, ok := this.Get(key); ok && nil != value {
+		sm, _ = value.(map[string]interface{})
+	}
+	return
+}
+
+func (this *Context) GetStringMapString(key string) (sms map[string]string) {
+	if value, ok := this.Get(key); ok && nil != value {
+		sms, _ = value.(map[string]string)
+	}
+	return
+}
+
+//Deadline 以下四个方法让 Context 实现 context.Context, 行为与 Request.Context() 一致
+func (this *Context) Deadline() (deadline time.Time, ok bool) {
+	if nil == this.Request {
+		return
+	}
+	return this.Request.Context().Deadline()
+}
+
+//Done 客户端断开或服务器关闭时被关闭
+func (this *Context) Done() <-chan struct{} {
+	if nil == this.Request {
+		return nil
+	}
+	return this.Request.Context().Done()
+}
+
+func (this *Context) Err() error {
+	if nil == this.Request {
+		return nil
+	}
+	return this.Request.Context().Err()
+}
+
+//Value 字符串键优先从 Keys 中查找, 其余交给 Request.Context()
+func (this *Context) Value(key interface{}) interface{} {
+	if keyString, ok := key.(string); ok {
+		if value, exists := this.Get(keyString); exists {
+			return value
+		}
+	}
+	if nil == this.Request {
+		return nil
+	}
+	return this.Request.Context().Value(key)
 }
 
 func (this *Context) PostForm(key string) string {
@@ -61,8 +318,36 @@ func (this *Context) Query(key string) string {
 }
 
 func (this *Context) Param(key string) string {
-	value, _ := this.Params[key]
-	return value
+	return this.Params.ByName(key)
+}
+
+//ClientIP 返回客户端地址, 只有直接连接方是可信代理时才读取 X-Forwarded-For 和 X-Real-IP
+func (this *Context) ClientIP() string {
+	remoteIP, _, err := net.SplitHostPort(strings.TrimSpace(this.Request.RemoteAddr))
+	if nil != err {
+		remoteIP = strings.TrimSpace(this.Request.RemoteAddr)
+	}
+	if nil == this.engine || !this.engine.isTrustedProxy(net.ParseIP(remoteIP)) {
+		return remoteIP
+	}
+
+	//从右向左跳过可信代理, 第一个不可信的地址即客户端
+	if forwarded := this.Request.Header.Get("X-Forwarded-For"); forwarded != "" {
+		items := strings.Split(forwarded, ",")
+		for i := len(items) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(items[i]))
+			if nil == ip {
+				break
+			}
+			if i == 0 || !this.engine.isTrustedProxy(ip) {
+				return ip.String()
+			}
+		}
+	}
+	if realIP := net.ParseIP(strings.TrimSpace(this.Request.Header.Get("X-Real-IP"))); nil != realIP {
+		return realIP.String()
+	}
+	return remoteIP
 }
 
 func (this *Context) SetCode(code int) {
@@ -97,7 +382,7 @@ func (this *Context) WriteData(code int, data []byte) {
 func (this *Context) WriteHTML(code int, name string, data interface{}) {
 	this.SetCode(code)
 	this.SetHeader("Content-Type", "text/html")
-	if err := this.engine.htmlTemplates.ExecuteTemplate(this.Writer, name, data); err != nil {
+	if err := this.engine.html.execute(this.Writer, name, data); err != nil {
 		this.Fail(http.StatusInternalServerError, err.Error())
 	}
 }
@@ -0,0 +1,127 @@
+package dew
+
+import (
+	stdcontext "context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAbortAndErrors(t *testing.T) {
+	engine := CreateEngine()
+	var collected ErrorList
+	engine.Use(func(context *Context) {
+		context.Next()
+		collected = context.Errors.ByType(ErrorTypePublic)
+	})
+	engine.GET("/abort", func(context *Context) {
+		context.AbortWithError(http.StatusForbidden, errors.New("forbidden")).SetType(ErrorTypePublic)
+		if !context.IsAborted() {
+			t.Fatal("context should be aborted")
+		}
+	}, func(context *Context) {
+		t.Fatal("handler after Abort should not run")
+	})
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("GET", "/abort", nil))
+	if recorder.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", recorder.Code)
+	}
+	if len(collected) != 1 || collected.Last().Error() != "forbidden" {
+		t.Fatalf("middleware should see the error, got %v", collected)
+	}
+}
+
+func TestResponseWriter(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	context := CreateContext(recorder, httptest.NewRequest("GET", "/", nil))
+
+	context.SetCode(http.StatusCreated)
+	context.SetHeader("X-Late", "ok")
+	if context.Writer.Written() || context.Writer.Size() != -1 {
+		t.Fatal("SetCode should not write headers")
+	}
+	context.WriteData(http.StatusAccepted, []byte("hello"))
+	context.SetCode(http.StatusTeapot)
+
+	if recorder.Code != http.StatusAccepted || recorder.Header().Get("X-Late") != "ok" {
+		t.Fatalf("unexpected response %d %v", recorder.Code, recorder.Header())
+	}
+	if context.Writer.Status() != http.StatusAccepted || context.Writer.Size() != 5 {
+		t.Fatalf("unexpected status %d and size %d", context.Writer.Status(), context.Writer.Size())
+	}
+}
+
+func TestSetCodeOnly(t *testing.T) {
+	engine := CreateEngine()
+	engine.DELETE("/item", func(context *Context) {
+		context.SetCode(http.StatusNoContent)
+	})
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("DELETE", "/item", nil))
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", recorder.Code)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	engine := CreateEngine()
+	engine.Use(func(context *Context) {
+		context.Set("user", "geek")
+		context.Set("id", 42)
+		context.Next()
+	})
+	engine.GET("/me", func(context *Context) {
+		if context.GetString("user") != "geek" || context.GetInt("id") != 42 || context.MustGet("id") != 42 {
+			t.Fatal("handler should see values set by middleware")
+		}
+		if _, ok := context.Get("missing"); ok {
+			t.Fatal("missing key should not exist")
+		}
+		if context.GetInt("user") != 0 {
+			t.Fatal("type mismatch should return the zero value")
+		}
+		copied := context.Copy()
+		if copied.GetString("user") != "geek" {
+			t.Fatal("copy should keep the keys")
+		}
+		//副本写响应不会 panic, 也不会影响原请求
+		copied.SetHeader("X-Copy", "1")
+		copied.WriteString(http.StatusTeapot, "from copy")
+		if _, err := copied.Writer.Write([]byte("x")); err != ErrCopiedContext {
+			t.Fatalf("writing from a copy should fail, got %v", err)
+		}
+		context.WriteString(http.StatusOK, "ok")
+	})
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("GET", "/me", nil))
+	if recorder.Body.String() != "ok" || recorder.Header().Get("X-Copy") != "" {
+		t.Fatalf("unexpected body %q", recorder.Body.String())
+	}
+}
+
+type contextKey string
+
+func TestContextInterface(t *testing.T) {
+	parent, cancel := stdcontext.WithTimeout(stdcontext.WithValue(stdcontext.Background(), contextKey("trace"), "abc"), time.Minute)
+	request := httptest.NewRequest("GET", "/", nil).WithContext(parent)
+	context := CreateContext(httptest.NewRecorder(), request)
+	context.Set("user", "geek")
+
+	var ctx stdcontext.Context = context
+	if _, ok := ctx.Deadline(); !ok {
+		t.Fatal("deadline should come from the request context")
+	}
+	if ctx.Value("user") != "geek" || ctx.Value(contextKey("trace")) != "abc" {
+		t.Fatal("Value should read Keys first and then the request context")
+	}
+	cancel()
+	<-ctx.Done()
+	if ctx.Err() != stdcontext.Canceled {
+		t.Fatalf("unexpected error %v", ctx.Err())
+	}
+}
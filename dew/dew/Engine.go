@@ -1,9 +1,12 @@
 package dew
 
 import (
-	"html/template"
+	"fmt"
+	"net"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 )
 
 type (
@@ -13,64 +16,194 @@ type (
 		*RouterGroup
 		router *router
 		groups []*RouterGroup
+		pool   sync.Pool
 		//对html渲染
-		htmlTemplates *template.Template
-		functionMap   template.FuncMap
+		html htmlLoader
+		//未匹配路由时的处理器
+		noRoute  []HandlerFunction
+		noMethod []HandlerFunction
+		//内容协商使用的渲染器, offered 为协商时的默认顺序
+		renderers map[string]Renderer
+		offered   []string
+		//WriteSecureJson 输出数组时使用的前缀
+		SecureJsonPrefix string
+		//MaxMultipartMemory 解析 multipart 表单时最多放在内存中的字节数, 超出部分写入临时文件
+		MaxMultipartMemory int64
+		//MaxBodyBytes 请求体的最大字节数, 0 表示不限制
+		MaxBodyBytes int64
+		//Run 系列方法创建的服务器使用的超时配置, 0 表示不限制
+		ReadTimeout       time.Duration
+		ReadHeaderTimeout time.Duration
+		WriteTimeout      time.Duration
+		IdleTimeout       time.Duration
+		MaxHeaderBytes    int
+		//可信代理, 来自这些地址的请求才会读取 X-Forwarded-For
+		trustedProxies []*net.IPNet
+		//已启动的服务器, 供 Shutdown 使用
+		servers      []*http.Server
+		serversMutex sync.Mutex
+		//shuttingDown 调用过 Shutdown 后不再启动新的服务器
+		shuttingDown bool
+		shutdownDone chan struct{}
+		shutdownOnce sync.Once
 	}
 )
 
+func defaultNoRoute(context *Context) {
+	context.WriteString(http.StatusNotFound, "404 NOT FOUND: %s\n", context.Path)
+}
+
+func defaultNoMethod(context *Context) {
+	context.WriteString(http.StatusMethodNotAllowed, "405 METHOD NOT ALLOWED: %s %s\n", context.Method, context.Path)
+}
+
 func CreateEngine() *Engine {
 	engine := &Engine{
-		router: createRouter(),
+		router:    createRouter(),
+		noRoute:   []HandlerFunction{defaultNoRoute},
+		noMethod:  []HandlerFunction{defaultNoMethod},
+		renderers: defaultRenderers(),
+		offered: []string{
+			"application/json", "application/xml", "text/xml",
+			"application/x-yaml", "application/yaml", "text/yaml", "text/plain",
+		},
+		SecureJsonPrefix:   defaultSecureJsonPrefix,
+		MaxMultipartMemory: defaultMultipartMemory,
+		shutdownDone:       make(chan struct{}),
 	}
 	engine.RouterGroup = &RouterGroup{
 		engine: engine,
 	}
 	engine.groups = []*RouterGroup{engine.RouterGroup}
+	engine.pool.New = func() interface{} {
+		return engine.allocateContext()
+	}
 	return engine
 }
 
+func (this *Engine) allocateContext() *Context {
+	return &Context{
+		Params: make(Params, 0, this.router.maxParams),
+		engine: this,
+	}
+}
+
 func Default() *Engine {
 	engine := CreateEngine()
 	engine.Use(Logger(), Recovery())
 	return engine
 }
 
-//自定义渲染函数
-func (this *Engine) SetFunctionMap(functionMap template.FuncMap) {
-	this.functionMap = functionMap
+//SetTrustedProxies 设置可信代理的 IP 或 CIDR, 默认不信任任何代理
+func (this *Engine) SetTrustedProxies(proxies []string) error {
+	trusted := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if nil == ip {
+				return fmt.Errorf("dew: invalid proxy address '%s'", proxy)
+			}
+			bits := 32
+			if nil == ip.To4() {
+				bits = 128
+			}
+			proxy = fmt.Sprintf("%s/%d", proxy, bits)
+		}
+		_, cidr, err := net.ParseCIDR(proxy)
+		if nil != err {
+			return err
+		}
+		trusted = append(trusted, cidr)
+	}
+	this.trustedProxies = trusted
+	return nil
 }
 
-func (this *Engine) LoadHTMLGlob(pattern string) {
-	this.htmlTemplates = template.Must(template.New("").Funcs(this.functionMap).ParseGlob(pattern))
+func (this *Engine) isTrustedProxy(ip net.IP) bool {
+	if nil == ip {
+		return false
+	}
+	for _, cidr := range this.trustedProxies {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
 }
 
-func (this *Engine) addRoute(method, pattern string, handler HandlerFunction) {
-	this.router.addRoute(method, pattern, handler)
+//NoRoute 设置路径不存在时的处理器, 处理器在分组中间件之后执行
+func (this *Engine) NoRoute(handlers ...HandlerFunction) {
+	this.noRoute = handlers
 }
 
-func (this *Engine) GET(pattern string, handler HandlerFunction) {
-	this.addRoute("GET", pattern, handler)
+//NoMethod 设置路径存在但请求方法不匹配时的处理器, 响应已带有 Allow 头
+func (this *Engine) NoMethod(handlers ...HandlerFunction) {
+	this.noMethod = handlers
 }
 
-func (this *Engine) POST(pattern string, handler HandlerFunction) {
-	this.addRoute("POST", pattern, handler)
+//matchMiddlewares 按路径段收集所有匹配分组的中间件
+func (this *Engine) matchMiddlewares(path string) []HandlerFunction {
+	var middlewares []HandlerFunction
+	for _, group := range this.groups {
+		if group.match(path) {
+			middlewares = append(middlewares, group.middlewares...)
+		}
+	}
+	return middlewares
 }
 
-//Run 定义了启动http服务器的方法
-func (this *Engine) Run(host string) error {
-	return http.ListenAndServe(host, this)
+//combineHandlers 计算路由的完整处理链: 分组中间件在前, 路由自身的处理器在后
+func (this *Engine) combineHandlers(pattern string, handlers []HandlerFunction) []HandlerFunction {
+	middlewares := this.matchMiddlewares(pattern)
+	chain := make([]HandlerFunction, 0, len(middlewares)+len(handlers))
+	chain = append(chain, middlewares...)
+	return append(chain, handlers...)
 }
 
-func (this *Engine) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
-	var middlewares []HandlerFunction
-	for _, group := range this.groups {
-		if strings.HasPrefix(request.URL.Path, group.prefix) {
-			middlewares = append(middlewares, group.middlewares...)
+//rebuildChains 中间件变化后重新计算所有路由的处理链
+func (this *Engine) rebuildChains() {
+	for method := range this.router.roots {
+		for _, n := range this.router.getRouters(method) {
+			n.chain = this.combineHandlers(n.pattern, n.handlers)
 		}
 	}
-	context := CreateContext(writer, request)
-	context.handlers = middlewares
+}
+
+//CreateContext 创建一个关联到该 Engine 的 Context, 不经过对象池, 主要用于测试
+func (this *Engine) CreateContext(writer http.ResponseWriter, request *http.Request) *Context {
+	context := this.allocateContext()
+	context.reset(writer, request)
+	return context
+}
+
+//HandleContext 按 context.Request 重新路由并执行处理器链, 可用于内部重定向和测试
+//在处理器中调用时保留 Keys 和 Errors, 返回后恢复原来的路由状态并中止当前处理器链
+func (this *Engine) HandleContext(context *Context) {
+	handlers, index, params := context.handlers, context.index, context.Params
+	path, method := context.Path, context.Method
+
 	context.engine = this
+	context.Path = context.Request.URL.Path
+	context.Method = context.Request.Method
+	//置空后由 router.handle 分配新的缓冲区, 不覆盖调用方的路由参数
+	context.Params = nil
+	context.handlers, context.index = nil, -1
+	this.router.handle(context)
+	context.Writer.WriteHeaderNow()
+
+	context.handlers, context.index, context.Params = handlers, index, params
+	context.Path, context.Method = path, method
+	context.Abort()
+}
+
+func (this *Engine) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	context := this.pool.Get().(*Context)
+	context.reset(writer, request)
+	if this.MaxBodyBytes > 0 && nil != request.Body && request.Body != http.NoBody {
+		request.Body = http.MaxBytesReader(writer, request.Body, this.MaxBodyBytes)
+	}
 	this.router.handle(context)
+	//只调用了 SetCode 的处理器也要把状态码写出去
+	context.Writer.WriteHeaderNow()
+	this.pool.Put(context)
 }
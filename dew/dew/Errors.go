@@ -0,0 +1,131 @@
+package dew
+
+import (
+	"fmt"
+	"strings"
+)
+
+//ErrorType 错误分类, 可以按位组合
+type ErrorType uint64
+
+const (
+	//ErrorTypeBind 绑定请求数据失败
+	ErrorTypeBind ErrorType = 1 << 63
+	//ErrorTypeRender 渲染响应失败
+	ErrorTypeRender ErrorType = 1 << 62
+	//ErrorTypePrivate 只供服务端记录
+	ErrorTypePrivate ErrorType = 1 << 0
+	//ErrorTypePublic 可以展示给客户端
+	ErrorTypePublic ErrorType = 1 << 1
+	//ErrorTypeAny 匹配任意类型
+	ErrorTypeAny ErrorType = 1<<64 - 1
+)
+
+type (
+	//Error 处理过程中收集的错误, Meta 可以附带任意上下文信息
+	Error struct {
+		Err  error
+		Type ErrorType
+		Meta interface{}
+	}
+
+	//ErrorList 当前请求收集到的全部错误, 中间件可以在 Next() 之后检查
+	ErrorList []*Error
+)
+
+func (this *Error) Error() string {
+	return this.Err.Error()
+}
+
+func (this *Error) Unwrap() error {
+	return this.Err
+}
+
+func (this *Error) SetType(errorType ErrorType) *Error {
+	this.Type = errorType
+	return this
+}
+
+func (this *Error) SetMeta(meta interface{}) *Error {
+	this.Meta = meta
+	return this
+}
+
+func (this *Error) IsType(errorType ErrorType) bool {
+	return this.Type&errorType > 0
+}
+
+//JSON 返回便于序列化的形式, Meta 为 H 时合并到结果中
+func (this *Error) JSON() interface{} {
+	result := H{}
+	if meta, ok := this.Meta.(H); ok {
+		for key, value := range meta {
+			result[key] = value
+		}
+	} else if nil != this.Meta {
+		result["meta"] = this.Meta
+	}
+	if _, ok := result["error"]; !ok {
+		result["error"] = this.Error()
+	}
+	return result
+}
+
+//ByType 返回指定类型的错误
+func (this ErrorList) ByType(errorType ErrorType) ErrorList {
+	if len(this) == 0 {
+		return nil
+	}
+	if errorType == ErrorTypeAny {
+		return this
+	}
+	var result ErrorList
+	for _, item := range this {
+		if item.IsType(errorType) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+//Last 返回最后一个错误, 没有时返回 nil
+func (this ErrorList) Last() *Error {
+	if length := len(this); length > 0 {
+		return this[length-1]
+	}
+	return nil
+}
+
+//Errors 返回所有错误信息
+func (this ErrorList) Errors() []string {
+	messages := make([]string, len(this))
+	for i, item := range this {
+		messages[i] = item.Error()
+	}
+	return messages
+}
+
+func (this ErrorList) JSON() interface{} {
+	switch len(this) {
+	case 0:
+		return nil
+	case 1:
+		return this[0].JSON()
+	}
+	result := make([]interface{}, len(this))
+	for i, item := range this {
+		result[i] = item.JSON()
+	}
+	return result
+}
+
+func (this ErrorList) String() string {
+	var builder strings.Builder
+	for i, item := range this {
+		fmt.Fprintf(&builder, "Error #%02d: %s\n", i+1, item.Err)
+		if nil != item.Meta {
+			fmt.Fprintf(&builder, "     Meta: %v\n", item.Meta)
+		}
+	}
+	return builder.String()
+}
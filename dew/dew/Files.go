@@ -0,0 +1,95 @@
+package dew
+
+import (
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//multipartMemory 没有关联 Engine 的 Context 使用默认值
+func (this *Context) multipartMemory() int64 {
+	if nil == this.engine {
+		return defaultMultipartMemory
+	}
+	return this.engine.MaxMultipartMemory
+}
+
+//MultipartForm 解析 multipart 表单, 内存中最多保存 Engine.MaxMultipartMemory 字节
+func (this *Context) MultipartForm() (*multipart.Form, error) {
+	if err := this.Request.ParseMultipartForm(this.multipartMemory()); nil != err {
+		return nil, err
+	}
+	return this.Request.MultipartForm, nil
+}
+
+//FormFile 返回表单中名为 name 的第一个文件
+func (this *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	if nil == this.Request.MultipartForm {
+		if err := this.Request.ParseMultipartForm(this.multipartMemory()); nil != err {
+			return nil, err
+		}
+	}
+	file, header, err := this.Request.FormFile(name)
+	if nil != err {
+		return nil, err
+	}
+	file.Close()
+	return header, nil
+}
+
+//SaveUploadedFile 把上传的文件保存到 dst, 目录不存在时自动创建
+func (this *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	source, err := file.Open()
+	if nil != err {
+		return err
+	}
+	defer source.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0750); nil != err {
+		return err
+	}
+	out, err := os.Create(dst)
+	if nil != err {
+		return err
+	}
+	if _, err := io.Copy(out, source); nil != err {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+//File 发送本地文件, 支持 Range 和 If-Modified-Since
+func (this *Context) File(filePath string) {
+	http.ServeFile(this.Writer, this.Request, filePath)
+}
+
+//FileAttachment 以附件形式发送文件, 浏览器会以 filename 为名下载
+func (this *Context) FileAttachment(filePath, filename string) {
+	this.SetHeader("Content-Disposition", contentDisposition(filename))
+	http.ServeFile(this.Writer, this.Request, filePath)
+}
+
+//FileFromFS 从 fileSystem 中发送文件, filePath 为其中的路径
+func (this *Context) FileFromFS(filePath string, fileSystem http.FileSystem) {
+	defer func(old string) {
+		this.Request.URL.Path = old
+	}(this.Request.URL.Path)
+
+	this.Request.URL.Path = filePath
+	http.FileServer(fileSystem).ServeHTTP(this.Writer, this.Request)
+}
+
+//contentDisposition 非 ASCII 的文件名按 RFC 6266 使用 filename* 编码
+func contentDisposition(filename string) string {
+	for _, char := range filename {
+		if char >= 0x80 || char < 0x20 {
+			return `attachment; filename*=UTF-8''` + url.PathEscape(filename)
+		}
+	}
+	return `attachment; filename="` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(filename) + `"`
+}
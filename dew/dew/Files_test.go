@@ -0,0 +1,124 @@
+package dew
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func multipartRequest(t *testing.T, field, filename, content string) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile(field, filename)
+	if nil != err {
+		t.Fatal(err)
+	}
+	part.Write([]byte(content))
+	writer.WriteField("title", "cover")
+	writer.Close()
+	request := httptest.NewRequest("POST", "/upload", &body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	return request
+}
+
+func TestUpload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dew-upload")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	engine := CreateEngine()
+	engine.POST("/upload", func(context *Context) {
+		file, err := context.FormFile("image")
+		if nil != err {
+			context.Fail(http.StatusBadRequest, err)
+			return
+		}
+		form, _ := context.MultipartForm()
+		if err := context.SaveUploadedFile(file, filepath.Join(dir, "covers", file.Filename)); nil != err {
+			context.Fail(http.StatusInternalServerError, err)
+			return
+		}
+		context.WriteString(http.StatusOK, form.Value["title"][0])
+	})
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, multipartRequest(t, "image", "a.png", "png data"))
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "cover" {
+		t.Fatalf("upload failed: %d %s", recorder.Code, recorder.Body.String())
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "covers", "a.png")); string(data) != "png data" {
+		t.Fatalf("unexpected saved content %q", data)
+	}
+
+	recorder = httptest.NewRecorder()
+	engine.ServeHTTP(recorder, multipartRequest(t, "other", "a.png", "png data"))
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("missing file should fail, got %d", recorder.Code)
+	}
+
+	engine.MaxBodyBytes = 16
+	recorder = httptest.NewRecorder()
+	engine.ServeHTTP(recorder, multipartRequest(t, "image", "a.png", "png data"))
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("oversized body should fail, got %d", recorder.Code)
+	}
+}
+
+func TestFileResponses(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dew-file")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "report.txt")
+	ioutil.WriteFile(path, []byte("0123456789"), 0644)
+	modified := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	os.Chtimes(path, modified, modified)
+
+	engine := CreateEngine()
+	engine.GET("/file", func(context *Context) {
+		context.File(path)
+	})
+	engine.GET("/download", func(context *Context) {
+		context.FileAttachment(path, "报表.txt")
+	})
+	engine.GET("/fs", func(context *Context) {
+		context.FileFromFS("report.txt", http.Dir(dir))
+	})
+
+	serve := func(target string, headers map[string]string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("GET", target, nil)
+		for key, value := range headers {
+			request.Header.Set(key, value)
+		}
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	if recorder := serve("/file", map[string]string{"Range": "bytes=2-4"}); recorder.Code != http.StatusPartialContent || recorder.Body.String() != "234" {
+		t.Fatalf("range request failed: %d %q", recorder.Code, recorder.Body.String())
+	}
+	since := modified.Add(time.Hour).Format(http.TimeFormat)
+	if recorder := serve("/file", map[string]string{"If-Modified-Since": since}); recorder.Code != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d", recorder.Code)
+	}
+	recorder := serve("/download", nil)
+	if recorder.Header().Get("Content-Disposition") != "attachment; filename*=UTF-8''%E6%8A%A5%E8%A1%A8.txt" {
+		t.Fatalf("unexpected disposition %q", recorder.Header().Get("Content-Disposition"))
+	}
+	if recorder := serve("/fs", nil); recorder.Code != http.StatusOK || recorder.Body.String() != "0123456789" {
+		t.Fatalf("file from fs failed: %d %q", recorder.Code, recorder.Body.String())
+	}
+	if contentDisposition(`a"b.txt`) != `attachment; filename="a\"b.txt"` {
+		t.Fatal("quotes should be escaped")
+	}
+}
@@ -0,0 +1,303 @@
+package dew
+
+import (
+	"errors"
+	"html/template"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+//htmlLoader 记录模板的来源和解析选项, 以便在选项变化或 debug 模式下文件变化时重新解析
+type htmlLoader struct {
+	mutex sync.RWMutex
+	//templates 没有布局时包含所有模板, 有布局时只包含布局和局部模板
+	templates *template.Template
+	//pages 有布局时每个页面单独一个模板集, 页面中的 define 可以覆盖布局中的 block
+	pages       map[string]*template.Template
+	functionMap template.FuncMap
+	delims      [2]string
+	layouts     []string
+	//fileSystem 为 nil 时从磁盘读取, exact 表示 patterns 是文件名而不是通配符
+	fileSystem fs.FS
+	patterns   []string
+	exact      bool
+	loaded     bool
+	//stale 选项在加载之后被修改, 下次渲染前需要重新解析
+	stale    bool
+	modTimes map[string]time.Time
+	//scanned 上次重新匹配通配符的时间
+	scanned time.Time
+}
+
+//htmlScanInterval debug 模式下每次渲染只检查已加载文件的修改时间, 新增的文件最多每隔这么久重新匹配一次通配符
+const htmlScanInterval = time.Second
+
+func (this *htmlLoader) glob(pattern string) ([]string, error) {
+	if nil != this.fileSystem {
+		return fs.Glob(this.fileSystem, pattern)
+	}
+	return filepath.Glob(pattern)
+}
+
+//list 列出所有文件, 重复的只保留一次
+func (this *htmlLoader) list(patterns []string, exact bool) ([]string, error) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		matches := []string{pattern}
+		if !exact {
+			var err error
+			if matches, err = this.glob(pattern); nil != err {
+				return nil, err
+			}
+			if len(matches) == 0 {
+				return nil, errors.New("dew: html/template: pattern matches no files: " + pattern)
+			}
+		}
+		for _, name := range matches {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names, nil
+}
+
+func (this *htmlLoader) read(name string) ([]byte, error) {
+	if nil != this.fileSystem {
+		return fs.ReadFile(this.fileSystem, name)
+	}
+	return ioutil.ReadFile(name)
+}
+
+func (this *htmlLoader) modTime(name string) time.Time {
+	var info os.FileInfo
+	var err error
+	if nil != this.fileSystem {
+		info, err = fs.Stat(this.fileSystem, name)
+	} else {
+		info, err = os.Stat(name)
+	}
+	if nil != err {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+//baseName 与 template.ParseGlob 一样以文件名作为模板名
+func (this *htmlLoader) baseName(name string) string {
+	if nil != this.fileSystem {
+		return path.Base(name)
+	}
+	return filepath.Base(name)
+}
+
+//parse 把 names 中的文件依次加入 set
+func (this *htmlLoader) parse(set *template.Template, names []string) error {
+	for _, name := range names {
+		data, err := this.read(name)
+		if nil != err {
+			return err
+		}
+		if _, err := set.New(this.baseName(name)).Parse(string(data)); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+//load 重新解析所有模板, 调用方需持有写锁
+func (this *htmlLoader) load() error {
+	layouts, err := this.list(this.layouts, false)
+	if nil != err {
+		return err
+	}
+	files, err := this.list(this.patterns, this.exact)
+	if nil != err {
+		return err
+	}
+	isLayout := make(map[string]bool, len(layouts))
+	for _, name := range layouts {
+		isLayout[name] = true
+	}
+	modTimes := make(map[string]time.Time, len(layouts)+len(files))
+	for _, name := range append(layouts, files...) {
+		modTimes[name] = this.modTime(name)
+	}
+
+	root := template.New("").Delims(this.delims[0], this.delims[1]).Funcs(this.functionMap)
+	if len(layouts) == 0 {
+		if err := this.parse(root, files); nil != err {
+			return err
+		}
+		this.templates, this.pages = root, nil
+		this.reset(modTimes)
+		return nil
+	}
+
+	if err := this.parse(root, layouts); nil != err {
+		return err
+	}
+	pages := make(map[string]*template.Template)
+	for _, name := range files {
+		if isLayout[name] {
+			continue
+		}
+		page, err := root.Clone()
+		if nil != err {
+			return err
+		}
+		if err := this.parse(page, []string{name}); nil != err {
+			return err
+		}
+		pages[this.baseName(name)] = page
+	}
+	this.templates, this.pages = root, pages
+	this.reset(modTimes)
+	return nil
+}
+
+//reset 解析成功后记录状态, 调用方需持有写锁
+func (this *htmlLoader) reset(modTimes map[string]time.Time) {
+	this.modTimes = modTimes
+	this.scanned = time.Now()
+	this.stale = false
+}
+
+//modified 已加载的文件是否被修改或删除
+func (this *htmlLoader) modified() bool {
+	for name, modTime := range this.modTimes {
+		if !this.modTime(name).Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+//added 重新匹配通配符, 检查是否有新增的文件
+func (this *htmlLoader) added() bool {
+	layouts, err := this.list(this.layouts, false)
+	if nil != err {
+		return true
+	}
+	files, err := this.list(this.patterns, this.exact)
+	if nil != err {
+		return true
+	}
+	for _, name := range append(layouts, files...) {
+		if _, ok := this.modTimes[name]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+//outdated 是否可能需要重新解析, debug 模式下会检查已加载文件的修改时间, 调用方需持有读锁
+func (this *htmlLoader) outdated(debug bool) bool {
+	if !this.loaded {
+		return false
+	}
+	return this.stale || debug && (this.modified() || time.Since(this.scanned) >= htmlScanInterval)
+}
+
+//refresh 确认需要时重新解析, 调用方需持有写锁
+func (this *htmlLoader) refresh(debug bool) error {
+	if !this.outdated(debug) {
+		return nil
+	}
+	if !this.stale && !this.modified() {
+		//只是到了重新匹配通配符的时间
+		this.scanned = time.Now()
+		if !this.added() {
+			return nil
+		}
+	}
+	return this.load()
+}
+
+//execute 执行名为 name 的模板, 选项被修改或 debug 模式下文件变化时先重新解析
+//debug 模式下每次渲染都会 stat 所有已加载的模板文件, 生产环境应使用 release 模式
+func (this *htmlLoader) execute(writer io.Writer, name string, data interface{}) error {
+	debug := IsDebugging()
+	this.mutex.RLock()
+	outdated := this.outdated(debug)
+	this.mutex.RUnlock()
+	if outdated {
+		this.mutex.Lock()
+		err := this.refresh(debug)
+		this.mutex.Unlock()
+		if nil != err {
+			return err
+		}
+	}
+
+	this.mutex.RLock()
+	templates, page := this.templates, this.pages[name]
+	this.mutex.RUnlock()
+	if nil != page {
+		return page.ExecuteTemplate(writer, name, data)
+	}
+	if nil == templates {
+		return errors.New("dew: html templates are not loaded")
+	}
+	return templates.ExecuteTemplate(writer, name, data)
+}
+
+//SetFunctionMap 设置模板函数, 在加载模板之后调用时于下次渲染前重新解析
+func (this *Engine) SetFunctionMap(functionMap template.FuncMap) {
+	this.html.mutex.Lock()
+	defer this.html.mutex.Unlock()
+	this.html.functionMap = functionMap
+	this.html.stale = true
+}
+
+//Delims 设置模板的左右分隔符, 例如 "{[{" 和 "}]}", 同样在下次渲染前生效
+func (this *Engine) Delims(left, right string) {
+	this.html.mutex.Lock()
+	defer this.html.mutex.Unlock()
+	this.html.delims = [2]string{left, right}
+	this.html.stale = true
+}
+
+//SetHTMLLayouts 设置布局和局部模板的通配符, 路径与加载模板时的来源相同
+//匹配的文件会加入每个页面的模板集, 页面可以用 {{template "layout.tmpl" .}} 套用布局并以 define 覆盖其中的 block
+func (this *Engine) SetHTMLLayouts(patterns ...string) {
+	this.html.mutex.Lock()
+	defer this.html.mutex.Unlock()
+	this.html.layouts = patterns
+	this.html.stale = true
+}
+
+//loadHTML 替换模板来源并立即解析, 解析失败时 panic
+func (this *Engine) loadHTML(fileSystem fs.FS, patterns []string, exact bool) {
+	this.html.mutex.Lock()
+	defer this.html.mutex.Unlock()
+	this.html.fileSystem = fileSystem
+	this.html.patterns = patterns
+	this.html.exact = exact
+	if err := this.html.load(); nil != err {
+		panic(err)
+	}
+	this.html.loaded = true
+}
+
+func (this *Engine) LoadHTMLGlob(pattern string) {
+	this.loadHTML(nil, []string{pattern}, false)
+}
+
+//LoadHTMLFiles 加载指定的模板文件
+func (this *Engine) LoadHTMLFiles(files ...string) {
+	this.loadHTML(nil, files, true)
+}
+
+//LoadHTMLFS 从 fs.FS 中加载匹配 patterns 的模板, 可用于 embed.FS, 布局的通配符同样相对于 fileSystem
+func (this *Engine) LoadHTMLFS(fileSystem fs.FS, patterns ...string) {
+	this.loadHTML(fileSystem, patterns, false)
+}
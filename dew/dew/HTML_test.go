@@ -0,0 +1,123 @@
+package dew
+
+import (
+	"html/template"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func renderHTML(engine *Engine, name string, data interface{}) string {
+	recorder := httptest.NewRecorder()
+	context := CreateContext(recorder, httptest.NewRequest("GET", "/", nil))
+	context.engine = engine
+	context.WriteHTML(200, name, data)
+	return recorder.Body.String()
+}
+
+func writeTemplates(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "dew-html")
+	if nil != err {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); nil != err {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadHTMLOptions(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"hello.tmpl": `Hello {[{ upper .name }]}`,
+	})
+	defer os.RemoveAll(dir)
+
+	engine := CreateEngine()
+	engine.Delims("{[{", "}]}")
+	engine.SetFunctionMap(template.FuncMap{"upper": strings.ToLower})
+	engine.LoadHTMLFiles(filepath.Join(dir, "hello.tmpl"))
+	//加载之后再设置函数同样生效
+	engine.SetFunctionMap(template.FuncMap{"upper": strings.ToUpper})
+	if body := renderHTML(engine, "hello.tmpl", H{"name": "dew"}); body != "Hello DEW" {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	//选项只在下次渲染时生效, 解析失败时返回错误而不是 panic
+	engine.SetFunctionMap(nil)
+	if body := renderHTML(engine, "hello.tmpl", H{"name": "dew"}); !strings.Contains(body, "500") {
+		t.Fatalf("parse errors should be reported, got %q", body)
+	}
+	engine.SetFunctionMap(template.FuncMap{"upper": strings.ToUpper})
+	if body := renderHTML(engine, "hello.tmpl", H{"name": "dew"}); body != "Hello DEW" {
+		t.Fatalf("templates should recover after a bad option, got %q", body)
+	}
+}
+
+func TestHTMLLayouts(t *testing.T) {
+	files := fstest.MapFS{
+		"layouts/base.tmpl":  {Data: []byte(`<title>{{block "title" .}}Shop{{end}}</title>{{template "nav.tmpl"}}{{block "content" .}}{{end}}`)},
+		"partials/nav.tmpl":  {Data: []byte(`<nav></nav>`)},
+		"pages/index.tmpl":   {Data: []byte(`{{template "base.tmpl" .}}{{define "content"}}index{{end}}`)},
+		"pages/product.tmpl": {Data: []byte(`{{template "base.tmpl" .}}{{define "title"}}{{.}}{{end}}{{define "content"}}product{{end}}`)},
+	}
+	engine := CreateEngine()
+	engine.SetHTMLLayouts("layouts/*.tmpl", "partials/*.tmpl")
+	engine.LoadHTMLFS(files, "pages/*.tmpl")
+
+	if body := renderHTML(engine, "index.tmpl", nil); body != "<title>Shop</title><nav></nav>index" {
+		t.Fatalf("unexpected index %q", body)
+	}
+	if body := renderHTML(engine, "product.tmpl", "Book"); body != "<title>Book</title><nav></nav>product" {
+		t.Fatalf("unexpected product %q", body)
+	}
+	if body := renderHTML(engine, "nav.tmpl", nil); body != "<nav></nav>" {
+		t.Fatalf("partials should be renderable directly, got %q", body)
+	}
+}
+
+func TestHTMLReload(t *testing.T) {
+	defer SetMode(Mode())
+	dir := writeTemplates(t, map[string]string{"page.tmpl": `v1`})
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "page.tmpl")
+
+	engine := CreateEngine()
+	engine.LoadHTMLGlob(filepath.Join(dir, "*.tmpl"))
+	update := func(content string, offset time.Duration) {
+		ioutil.WriteFile(path, []byte(content), 0644)
+		modified := time.Now().Add(offset)
+		os.Chtimes(path, modified, modified)
+	}
+
+	SetMode(ReleaseMode)
+	update("v2", time.Hour)
+	if body := renderHTML(engine, "page.tmpl", nil); body != "v1" {
+		t.Fatalf("release mode should not reload, got %q", body)
+	}
+	SetMode(DebugMode)
+	if body := renderHTML(engine, "page.tmpl", nil); body != "v2" {
+		t.Fatalf("debug mode should reload, got %q", body)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "new.tmpl"), []byte("new"), 0644)
+	engine.html.scanned = time.Now().Add(time.Hour)
+	if body := renderHTML(engine, "new.tmpl", nil); !strings.Contains(body, "500") {
+		t.Fatalf("new files should not be globbed on every render, got %q", body)
+	}
+	engine.html.scanned = time.Time{}
+	if body := renderHTML(engine, "new.tmpl", nil); body != "new" {
+		t.Fatalf("new files should be picked up, got %q", body)
+	}
+	update("{{", 2*time.Hour)
+	if body := renderHTML(engine, "page.tmpl", nil); !strings.Contains(body, "500") {
+		t.Fatalf("parse errors should be reported, got %q", body)
+	}
+}
@@ -0,0 +1,183 @@
+package dew
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//JWTClaimsKey 校验通过的 JWTClaims 在 Context.Keys 中的键
+const JWTClaimsKey = "jwt_claims"
+
+var (
+	ErrTokenMissing     = errors.New("dew: token is missing")
+	ErrTokenMalformed   = errors.New("dew: token is malformed")
+	ErrTokenAlgorithm   = errors.New("dew: token algorithm is not allowed")
+	ErrTokenSignature   = errors.New("dew: token signature is invalid")
+	ErrTokenExpired     = errors.New("dew: token is expired")
+	ErrTokenNotValidYet = errors.New("dew: token is not valid yet")
+	ErrTokenAudience    = errors.New("dew: token audience is invalid")
+)
+
+type (
+	//JWTClaims JWT 载荷, 数字按 JSON 解码为 float64
+	JWTClaims map[string]interface{}
+
+	//JWTConfig 至少需要配置 Secret 或 PublicKey 之一, 只接受已配置密钥对应的算法
+	JWTConfig struct {
+		//Secret HS256 的密钥
+		Secret []byte
+		//PublicKey RS256 的公钥
+		PublicKey *rsa.PublicKey
+		//Audience 不为空时要求 aud 包含该值
+		Audience string
+		//Leeway 校验 exp 和 nbf 时允许的时钟偏差
+		Leeway time.Duration
+		//Lookup 取出 token, 默认读取 "Authorization: Bearer <token>"
+		Lookup func(context *Context) string
+	}
+)
+
+func (this JWTClaims) String(key string) string {
+	value, _ := this[key].(string)
+	return value
+}
+
+//time 读取 exp, nbf 等 NumericDate 字段, 字段不存在时 ok 为 false, 存在但不是数字时返回 ErrTokenMalformed
+func (this JWTClaims) time(key string) (value time.Time, ok bool, err error) {
+	raw, ok := this[key]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	seconds, isNumber := raw.(float64)
+	if !isNumber {
+		return time.Time{}, false, ErrTokenMalformed
+	}
+	return time.Unix(int64(seconds), 0), true, nil
+}
+
+//HasAudience aud 可以是字符串或字符串数组
+func (this JWTClaims) HasAudience(audience string) bool {
+	switch value := this["aud"].(type) {
+	case string:
+		return value == audience
+	case []interface{}:
+		for _, item := range value {
+			if item == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func bearerToken(context *Context) string {
+	header := context.Request.Header.Get("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+//ParseJWT 校验签名和 exp, nbf, aud, 返回载荷, exp, nbf, iat 存在但不是数字时视为格式错误
+func (this *JWTConfig) ParseJWT(token string) (JWTClaims, error) {
+	if token == "" {
+		return nil, ErrTokenMissing
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenMalformed
+	}
+	var header struct {
+		Algorithm string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); nil != err {
+		return nil, ErrTokenMalformed
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if nil != err {
+		return nil, ErrTokenMalformed
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	switch {
+	case header.Algorithm == "HS256" && len(this.Secret) > 0:
+		mac := hmac.New(sha256.New, this.Secret)
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return nil, ErrTokenSignature
+		}
+	case header.Algorithm == "RS256" && nil != this.PublicKey:
+		digest := sha256.Sum256(signed)
+		if nil != rsa.VerifyPKCS1v15(this.PublicKey, crypto.SHA256, digest[:], signature) {
+			return nil, ErrTokenSignature
+		}
+	default:
+		//包括 "none" 和未配置密钥的算法, 防止算法混淆攻击
+		return nil, ErrTokenAlgorithm
+	}
+
+	var claims JWTClaims
+	if err := decodeSegment(parts[1], &claims); nil != err || nil == claims {
+		return nil, ErrTokenMalformed
+	}
+	now := time.Now()
+	expires, hasExpires, err := claims.time("exp")
+	if nil != err {
+		return nil, err
+	}
+	if hasExpires && !now.Before(expires.Add(this.Leeway)) {
+		return nil, ErrTokenExpired
+	}
+	notBefore, hasNotBefore, err := claims.time("nbf")
+	if nil != err {
+		return nil, err
+	}
+	if hasNotBefore && now.Add(this.Leeway).Before(notBefore) {
+		return nil, ErrTokenNotValidYet
+	}
+	//iat 不参与有效期判断, 但格式错误同样说明签发方有问题
+	if _, _, err := claims.time("iat"); nil != err {
+		return nil, err
+	}
+	if this.Audience != "" && !claims.HasAudience(this.Audience) {
+		return nil, ErrTokenAudience
+	}
+	return claims, nil
+}
+
+func decodeSegment(segment string, value interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if nil != err {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
+
+//JWT 校验 token, 通过后载荷保存在 JWTClaimsKey 下, 失败时返回 401
+func JWT(config JWTConfig) HandlerFunction {
+	if len(config.Secret) == 0 && nil == config.PublicKey {
+		panic("dew: JWT requires Secret or PublicKey")
+	}
+	if nil == config.Lookup {
+		config.Lookup = bearerToken
+	}
+
+	return NameHandler("dew/dew.JWT", func(context *Context) {
+		claims, err := config.ParseJWT(config.Lookup(context))
+		if nil != err {
+			context.SetHeader("WWW-Authenticate", `Bearer error="invalid_token"`)
+			context.Fail(http.StatusUnauthorized, err)
+			return
+		}
+		context.Set(JWTClaimsKey, claims)
+		context.Next()
+	})
+}
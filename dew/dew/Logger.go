@@ -1,14 +1,188 @@
 package dew
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
 	"log"
+	"strings"
+	"sync"
 	"time"
 )
 
+//RequestIDKey 请求 ID 在 Context.Keys 中的键
+const RequestIDKey = "request_id"
+
+type (
+	//LogParams 一条访问日志包含的字段
+	LogParams struct {
+		TimeStamp    time.Time     `json:"time"`
+		Level        string        `json:"level"`
+		StatusCode   int           `json:"status"`
+		Latency      time.Duration `json:"latency"`
+		ClientIP     string        `json:"client_ip"`
+		Method       string        `json:"method"`
+		Path         string        `json:"path"`
+		BodySize     int           `json:"bytes"`
+		UserAgent    string        `json:"user_agent"`
+		RequestID    string        `json:"request_id"`
+		ErrorMessage string        `json:"error,omitempty"`
+	}
+
+	//LogFormatter 把 LogParams 格式化为一行日志, 结尾的换行由格式化函数负责
+	LogFormatter func(params LogParams) string
+
+	LoggerConfig struct {
+		//Output 日志输出, 默认与标准库 log 相同
+		Output io.Writer
+		//Formatter 默认为 TextFormatter
+		Formatter LogFormatter
+		//SkipPaths 不记录日志的请求路径
+		SkipPaths []string
+		//Skip 返回 true 时不记录日志
+		Skip func(context *Context) bool
+		//RequestIDHeader 读取和回写请求 ID 的头, 默认 X-Request-ID, 请求中没有或不合法时自动生成
+		RequestIDHeader string
+	}
+)
+
+//levelOf 按状态码分类: 5xx 为 ERROR, 4xx 为 WARN, 其余为 INFO
+func levelOf(code int) string {
+	switch {
+	case code >= 500:
+		return "ERROR"
+	case code >= 400:
+		return "WARN"
+	}
+	return "INFO"
+}
+
+//TextFormatter 默认的单行文本格式
+func TextFormatter(params LogParams) string {
+	line := fmt.Sprintf("[%s] %v | %3d | %13v | %15s | %-7s %s | %dB | %s | %s",
+		params.Level,
+		params.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		params.StatusCode,
+		params.Latency,
+		params.ClientIP,
+		params.Method,
+		params.Path,
+		params.BodySize,
+		params.UserAgent,
+		params.RequestID,
+	)
+	if params.ErrorMessage != "" {
+		line += " | " + strings.TrimSpace(params.ErrorMessage)
+	}
+	return line + "\n"
+}
+
+//JSONFormatter 每条日志一行 JSON, 便于日志系统采集
+func JSONFormatter(params LogParams) string {
+	data, err := json.Marshal(params)
+	if nil != err {
+		return fmt.Sprintf("{\"level\":\"ERROR\",\"error\":%q}\n", err.Error())
+	}
+	return string(data) + "\n"
+}
+
+//maxRequestIDLength 客户端传入的请求 ID 的最大长度
+const maxRequestIDLength = 128
+
+//validRequestID 只接受长度有限且不含空白和控制字符的可打印 ASCII, 防止日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	var data [16]byte
+	if _, err := rand.Read(data[:]); nil != err {
+		return ""
+	}
+	return hex.EncodeToString(data[:])
+}
+
 func Logger() HandlerFunction {
-	return func(context *Context) {
+	return LoggerWithConfig(LoggerConfig{})
+}
+
+//LoggerWithWriter 输出到 output, 并跳过 skipPaths 中的请求
+func LoggerWithWriter(output io.Writer, skipPaths ...string) HandlerFunction {
+	return LoggerWithConfig(LoggerConfig{
+		Output:    output,
+		SkipPaths: skipPaths,
+	})
+}
+
+func LoggerWithFormatter(formatter LogFormatter) HandlerFunction {
+	return LoggerWithConfig(LoggerConfig{
+		Formatter: formatter,
+	})
+}
+
+func LoggerWithConfig(config LoggerConfig) HandlerFunction {
+	output := config.Output
+	if nil == output {
+		output = log.Writer()
+	}
+	formatter := config.Formatter
+	if nil == formatter {
+		formatter = TextFormatter
+	}
+	header := config.RequestIDHeader
+	if header == "" {
+		header = "X-Request-ID"
+	}
+	var mutex sync.Mutex
+	skipPaths := make(map[string]bool, len(config.SkipPaths))
+	for _, path := range config.SkipPaths {
+		skipPaths[path] = true
+	}
+
+	return NameHandler("dew/dew.Logger", func(context *Context) {
 		start := time.Now()
+		requestID := context.Request.Header.Get(header)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		context.Set(RequestIDKey, requestID)
+		context.SetHeader(header, requestID)
+
 		context.Next()
-		log.Printf("[%d] %s in %v", context.Code, context.Request.RequestURI, time.Since(start))
-	}
+
+		if skipPaths[context.Path] || (nil != config.Skip && config.Skip(context)) {
+			return
+		}
+		status := context.Writer.Status()
+		size := context.Writer.Size()
+		if size < 0 {
+			size = 0
+		}
+		params := LogParams{
+			TimeStamp:    time.Now(),
+			Level:        levelOf(status),
+			StatusCode:   status,
+			Latency:      time.Since(start),
+			ClientIP:     context.ClientIP(),
+			Method:       context.Method,
+			Path:         context.Request.URL.RequestURI(),
+			BodySize:     size,
+			UserAgent:    context.Request.UserAgent(),
+			RequestID:    requestID,
+			ErrorMessage: context.Errors.ByType(ErrorTypePrivate).String(),
+		}
+		mutex.Lock()
+		io.WriteString(output, formatter(params))
+		mutex.Unlock()
+	})
 }
@@ -0,0 +1,87 @@
+package dew
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoggerWithConfig(t *testing.T) {
+	var buffer bytes.Buffer
+	engine := CreateEngine()
+	if err := engine.SetTrustedProxies([]string{"192.0.2.0/24"}); nil != err {
+		t.Fatal(err)
+	}
+	engine.Use(LoggerWithConfig(LoggerConfig{
+		Output:    &buffer,
+		Formatter: JSONFormatter,
+		SkipPaths: []string{"/health"},
+	}))
+	engine.GET("/hello", func(context *Context) {
+		context.WriteData(0, []byte("hello"))
+	})
+	engine.GET("/health", func(context *Context) {})
+
+	request := httptest.NewRequest("GET", "/hello?a=1", nil)
+	request.RemoteAddr = "192.0.2.1:1234"
+	request.Header.Set("X-Forwarded-For", "203.0.113.7, 192.0.2.9")
+	request.Header.Set("X-Request-ID", "abc")
+	request.Header.Set("User-Agent", "dew-test")
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/missing", nil))
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 log lines, got %q", buffer.String())
+	}
+	var params LogParams
+	if err := json.Unmarshal([]byte(lines[0]), &params); nil != err {
+		t.Fatal(err)
+	}
+	if params.StatusCode != http.StatusOK || params.Level != "INFO" || params.BodySize != 5 ||
+		params.ClientIP != "203.0.113.7" || params.Path != "/hello?a=1" ||
+		params.RequestID != "abc" || params.UserAgent != "dew-test" {
+		t.Fatalf("unexpected log params %+v", params)
+	}
+	if recorder.Header().Get("X-Request-ID") != "abc" {
+		t.Fatal("request id should be echoed in the response")
+	}
+	if !strings.Contains(lines[1], `"status":404`) || !strings.Contains(lines[1], `"level":"WARN"`) {
+		t.Fatalf("unexpected 404 log line %s", lines[1])
+	}
+}
+
+func TestInvalidRequestID(t *testing.T) {
+	var buffer bytes.Buffer
+	engine := CreateEngine()
+	engine.Use(LoggerWithWriter(&buffer))
+	engine.GET("/", func(context *Context) {})
+
+	for _, id := range []string{"abc\nforged log line", "with space", strings.Repeat("a", 129)} {
+		buffer.Reset()
+		request := httptest.NewRequest("GET", "/", nil)
+		request.Header["X-Request-Id"] = []string{id}
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, request)
+		replaced := recorder.Header().Get("X-Request-ID")
+		if replaced == id || len(replaced) != 32 || strings.Count(buffer.String(), "\n") != 1 {
+			t.Fatalf("invalid id %q should be replaced, got %q, log %q", id, replaced, buffer.String())
+		}
+	}
+}
+
+func TestClientIPUntrusted(t *testing.T) {
+	request := httptest.NewRequest("GET", "/", nil)
+	request.RemoteAddr = "198.51.100.1:1234"
+	request.Header.Set("X-Forwarded-For", "203.0.113.7")
+	context := CreateContext(httptest.NewRecorder(), request)
+	context.engine = CreateEngine()
+	if ip := context.ClientIP(); ip != "198.51.100.1" {
+		t.Fatalf("headers from untrusted peers must be ignored, got %s", ip)
+	}
+}
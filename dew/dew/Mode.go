@@ -0,0 +1,40 @@
+package dew
+
+import "os"
+
+//EnvDewMode 读取运行模式的环境变量
+const EnvDewMode = "DEW_MODE"
+
+const (
+	DebugMode   = "debug"
+	ReleaseMode = "release"
+	TestMode    = "test"
+)
+
+var dewMode = DebugMode
+
+func init() {
+	SetMode(os.Getenv(EnvDewMode))
+}
+
+//SetMode 设置运行模式, 空串表示 debug
+func SetMode(mode string) {
+	switch mode {
+	case "":
+		mode = DebugMode
+	case DebugMode, ReleaseMode, TestMode:
+	default:
+		panic("dew: unknown mode '" + mode + "', available modes: debug release test")
+	}
+	dewMode = mode
+}
+
+//Mode 返回当前运行模式
+func Mode() string {
+	return dewMode
+}
+
+//IsDebugging 是否处于 debug 模式
+func IsDebugging() bool {
+	return dewMode == DebugMode
+}
@@ -0,0 +1,185 @@
+package dew
+
+import (
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	//RateLimitResult 一次取令牌的结果
+	RateLimitResult struct {
+		Allowed bool
+		//Limit 桶容量
+		Limit int
+		//Remaining 本次之后剩余的令牌数
+		Remaining int
+		//RetryAfter 被拒绝时, 距离下一个令牌可用的时间
+		RetryAfter time.Duration
+		//Reset 距离令牌桶重新装满的时间
+		Reset time.Duration
+	}
+
+	//RateLimitStore 令牌桶存储, 实现该接口即可接入 Redis 等外部后端
+	RateLimitStore interface {
+		//Take 从 key 对应的桶中取一个令牌, rate 为每秒补充的令牌数, burst 为桶容量
+		Take(key string, rate float64, burst int) (RateLimitResult, error)
+	}
+
+	RateLimitConfig struct {
+		//Rate 每秒补充的令牌数
+		Rate float64
+		//Burst 桶容量, 即允许的突发请求数, 默认向上取整的 Rate
+		Burst int
+		//KeyFunc 限流维度, 默认按客户端 IP
+		KeyFunc func(context *Context) string
+		//Store 默认每个中间件实例使用独立的内存存储
+		Store RateLimitStore
+		//Prefix 写入 Store 的 key 前缀, 多个限流器共用一个 Store 时用来区分彼此的桶
+		//默认按创建顺序为每个实例生成, 多进程共用外部存储时应显式设置
+		Prefix string
+		//OnLimited 被限流时的处理器, 默认返回 JSON 429
+		OnLimited HandlerFunction
+	}
+
+	//tokenBucket 记录自己的 rate 和 burst, 清理时不依赖当前调用方的配置
+	tokenBucket struct {
+		tokens float64
+		last   time.Time
+		rate   float64
+		burst  int
+	}
+
+	rateLimitShard struct {
+		mutex      sync.Mutex
+		buckets    map[string]*tokenBucket
+		operations int
+	}
+
+	//MemoryRateLimitStore 按 key 哈希分片的内存令牌桶, 已装满的桶会被定期清理
+	MemoryRateLimitStore struct {
+		shards []*rateLimitShard
+		now    func() time.Time
+	}
+)
+
+//sweepInterval 每个分片每处理这么多次请求清理一次已装满的桶
+const sweepInterval = 1024
+
+//rateLimiters 已创建的限流器个数, 用于生成默认的 key 前缀
+var rateLimiters uint32
+
+//NewMemoryRateLimitStore 创建内存存储, shards 小于 1 时使用 32 个分片
+func NewMemoryRateLimitStore(shards int) *MemoryRateLimitStore {
+	if shards < 1 {
+		shards = 32
+	}
+	store := &MemoryRateLimitStore{
+		shards: make([]*rateLimitShard, shards),
+		now:    time.Now,
+	}
+	for i := range store.shards {
+		store.shards[i] = &rateLimitShard{buckets: make(map[string]*tokenBucket)}
+	}
+	return store
+}
+
+func (this *MemoryRateLimitStore) shard(key string) *rateLimitShard {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return this.shards[hash.Sum32()%uint32(len(this.shards))]
+}
+
+func (this *MemoryRateLimitStore) Take(key string, rate float64, burst int) (RateLimitResult, error) {
+	now := this.now()
+	shard := this.shard(key)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	shard.operations++
+	if shard.operations%sweepInterval == 0 {
+		//装满的桶与不存在等价, 删除以回收内存
+		for name, bucket := range shard.buckets {
+			if bucket.tokens+now.Sub(bucket.last).Seconds()*bucket.rate >= float64(bucket.burst) {
+				delete(shard.buckets, name)
+			}
+		}
+	}
+
+	bucket, ok := shard.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(burst), last: now}
+		shard.buckets[key] = bucket
+	}
+	bucket.rate, bucket.burst = rate, burst
+	bucket.tokens = math.Min(float64(burst), bucket.tokens+now.Sub(bucket.last).Seconds()*rate)
+	bucket.last = now
+
+	result := RateLimitResult{Limit: burst}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - bucket.tokens) / rate * float64(time.Second))
+	}
+	result.Remaining = int(bucket.tokens)
+	result.Reset = time.Duration((float64(burst) - bucket.tokens) / rate * float64(time.Second))
+	return result, nil
+}
+
+//ceilSeconds 向上取整为秒, 供 Retry-After 等头使用
+func ceilSeconds(duration time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(duration.Seconds())), 10)
+}
+
+//RateLimit 令牌桶限流中间件, 在分组上 Use 即可按分组配置不同的限额
+func RateLimit(config RateLimitConfig) HandlerFunction {
+	if config.Rate <= 0 {
+		panic("dew: rate limit must be positive")
+	}
+	if config.Burst <= 0 {
+		config.Burst = int(math.Ceil(config.Rate))
+	}
+	if nil == config.KeyFunc {
+		config.KeyFunc = func(context *Context) string {
+			return context.ClientIP()
+		}
+	}
+	if nil == config.Store {
+		config.Store = NewMemoryRateLimitStore(0)
+	}
+	if config.Prefix == "" {
+		config.Prefix = "ratelimit" + strconv.FormatUint(uint64(atomic.AddUint32(&rateLimiters, 1)), 10) + ":"
+	}
+	if nil == config.OnLimited {
+		config.OnLimited = func(context *Context) {
+			context.Fail(http.StatusTooManyRequests, "too many requests")
+		}
+	}
+
+	return NameHandler("dew/dew.RateLimit", func(context *Context) {
+		result, err := config.Store.Take(config.Prefix+config.KeyFunc(context), config.Rate, config.Burst)
+		if nil != err {
+			//存储不可用时放行, 避免限流组件拖垮整个服务
+			context.Error(err)
+			context.Next()
+			return
+		}
+
+		header := context.Writer.Header()
+		header.Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("X-RateLimit-Reset", ceilSeconds(result.Reset))
+		if !result.Allowed {
+			header.Set("Retry-After", ceilSeconds(result.RetryAfter))
+			config.OnLimited(context)
+			context.Abort()
+			return
+		}
+		context.Next()
+	})
+}
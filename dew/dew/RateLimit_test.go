@@ -0,0 +1,95 @@
+package dew
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	store := NewMemoryRateLimitStore(4)
+	now := time.Unix(0, 0)
+	store.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if result, _ := store.Take("ip", 1, 2); !result.Allowed || result.Remaining != 1-i {
+			t.Fatalf("request %d should be allowed, got %+v", i, result)
+		}
+	}
+	result, _ := store.Take("ip", 1, 2)
+	if result.Allowed || result.RetryAfter != time.Second || result.Reset != 2*time.Second {
+		t.Fatalf("bucket should be empty, got %+v", result)
+	}
+	if other, _ := store.Take("other", 1, 2); !other.Allowed {
+		t.Fatal("keys should have independent buckets")
+	}
+
+	now = now.Add(1500 * time.Millisecond)
+	if result, _ := store.Take("ip", 1, 2); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("bucket should be refilled, got %+v", result)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	engine := CreateEngine()
+	login := engine.Group("/login")
+	login.Use(RateLimit(RateLimitConfig{
+		Rate:    0.5,
+		Burst:   1,
+		KeyFunc: func(context *Context) string { return context.Request.Header.Get("X-API-Key") },
+	}))
+	login.POST("", func(context *Context) {
+		context.WriteString(http.StatusOK, "ok")
+	})
+
+	send := func(key string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("POST", "/login", nil)
+		request.Header.Set("X-API-Key", key)
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	if recorder := send("a"); recorder.Code != http.StatusOK || recorder.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Fatalf("first request should pass, got %d %v", recorder.Code, recorder.Header())
+	}
+	recorder := send("a")
+	if recorder.Code != http.StatusTooManyRequests || recorder.Header().Get("Retry-After") != "2" ||
+		recorder.Header().Get("X-RateLimit-Limit") != "1" {
+		t.Fatalf("second request should be limited, got %d %v", recorder.Code, recorder.Header())
+	}
+	if recorder := send("b"); recorder.Code != http.StatusOK {
+		t.Fatalf("another key should pass, got %d", recorder.Code)
+	}
+}
+
+func TestRateLimitSharedStore(t *testing.T) {
+	store := NewMemoryRateLimitStore(1)
+	engine := CreateEngine()
+	engine.Group("/login").Use(RateLimit(RateLimitConfig{Rate: 1, Burst: 1, Store: store}))
+	engine.Group("/search").Use(RateLimit(RateLimitConfig{Rate: 10, Burst: 5, Store: store}))
+	engine.GET("/login", func(context *Context) {})
+	engine.GET("/search", func(context *Context) {})
+
+	for _, path := range []string{"/login", "/search"} {
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("limiters sharing a store should not share buckets, %s got %d", path, recorder.Code)
+		}
+	}
+
+	//清理时按桶自己的配置判断是否装满
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	store.Take("slow", 0.001, 2)
+	for i := 1; i < sweepInterval; i++ {
+		//时钟前进后按 fast 的速率计算, slow 的桶会被误认为已装满
+		now = now.Add(10 * time.Millisecond)
+		store.Take("fast", 1000, 2)
+	}
+	if _, ok := store.shards[0].buckets["slow"]; !ok {
+		t.Fatal("sweep should not drop a bucket that is still refilling")
+	}
+}
@@ -1,11 +1,29 @@
 package dew
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"runtime"
 	"strings"
+	"syscall"
+)
+
+type (
+	//RecoveryFunc 处理 panic 的函数, err 为 recover() 的返回值
+	RecoveryFunc func(context *Context, err interface{})
+
+	RecoveryConfig struct {
+		//Output 输出 panic 日志, 默认与标准库 log 相同
+		Output io.Writer
+		//Handle 自定义响应, 默认在响应尚未写出时返回 JSON 500
+		Handle RecoveryFunc
+		//ShowStack 仅在 debug 模式下把调用栈写进默认响应
+		ShowStack bool
+	}
 )
 
 func trace(message string) string {
@@ -22,16 +40,92 @@ func trace(message string) string {
 	return str.String()
 }
 
+//isBrokenPipe 客户端断开导致的写失败不是程序错误, 不需要记录调用栈
+func isBrokenPipe(err interface{}) bool {
+	e, ok := err.(error)
+	if !ok {
+		return false
+	}
+	if errors.Is(e, syscall.EPIPE) || errors.Is(e, syscall.ECONNRESET) {
+		return true
+	}
+	var opError *net.OpError
+	if errors.As(e, &opError) {
+		message := strings.ToLower(opError.Err.Error())
+		return strings.Contains(message, "broken pipe") || strings.Contains(message, "connection reset by peer")
+	}
+	return false
+}
+
 func Recovery() HandlerFunction {
-	return func(context *Context) {
+	return RecoveryWithConfig(RecoveryConfig{})
+}
+
+//RecoveryWithWriter 把 panic 日志写到 output, 可选地指定自定义处理函数
+func RecoveryWithWriter(output io.Writer, recovery ...RecoveryFunc) HandlerFunction {
+	config := RecoveryConfig{Output: output}
+	if len(recovery) > 0 {
+		config.Handle = recovery[0]
+	}
+	return RecoveryWithConfig(config)
+}
+
+//CustomRecovery 使用自定义函数生成 panic 之后的响应
+func CustomRecovery(handle RecoveryFunc) HandlerFunction {
+	return RecoveryWithConfig(RecoveryConfig{Handle: handle})
+}
+
+func RecoveryWithConfig(config RecoveryConfig) HandlerFunction {
+	output := config.Output
+	if nil == output {
+		output = log.Writer()
+	}
+	logger := log.New(output, "", log.LstdFlags)
+
+	return NameHandler("dew/dew.Recovery", func(context *Context) {
 		defer func() {
-			if err := recover(); nil != err {
-				message := fmt.Sprintf("%s", err)
-				log.Printf("%s\n\n", trace(message))
-				context.Fail(http.StatusInternalServerError, "Internal Server Error")
+			err := recover()
+			if nil == err {
+				return
+			}
+			//交给 net/http 中断连接
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+
+			if isBrokenPipe(err) {
+				logger.Printf("connection closed by client: %s %s: %v\n", context.Method, context.Path, err)
+				context.Error(err.(error))
+				context.Abort()
+				return
 			}
+
+			message := fmt.Sprintf("%s", err)
+			stack := trace(message)
+			logger.Printf("%s\n\n", stack)
+
+			if nil != config.Handle {
+				config.Handle(context, err)
+				context.Abort()
+				return
+			}
+			//响应已经部分写出时无法再修改状态码
+			if context.Writer.Written() {
+				context.Abort()
+				return
+			}
+			body := H{
+				"code":    http.StatusInternalServerError,
+				"message": "Internal Server Error",
+			}
+			if config.ShowStack && IsDebugging() {
+				body["error"] = message
+				body["stack"] = strings.Split(stack, "\n\t")[1:]
+			}
+			context.Abort()
+			context.WriteJson(http.StatusInternalServerError, body)
 		}()
 
 		context.Next()
-	}
+	})
 }
@@ -0,0 +1,88 @@
+package dew
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestRecoveryWithWriter(t *testing.T) {
+	var buffer bytes.Buffer
+	engine := CreateEngine()
+	engine.Use(RecoveryWithWriter(&buffer))
+	engine.GET("/panic", func(context *Context) {
+		panic("boom")
+	})
+	engine.GET("/partial", func(context *Context) {
+		context.WriteString(http.StatusOK, "partial")
+		panic("boom")
+	})
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("GET", "/panic", nil))
+	if recorder.Code != http.StatusInternalServerError || !strings.Contains(buffer.String(), "boom\nTraceback:") {
+		t.Fatalf("panic should be logged and answered with 500, got %d", recorder.Code)
+	}
+	if strings.Contains(recorder.Body.String(), "stack") {
+		t.Fatal("stack should not be rendered by default")
+	}
+
+	recorder = httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("GET", "/partial", nil))
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "partial" {
+		t.Fatalf("written response should be left untouched, got %d %q", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestCustomRecovery(t *testing.T) {
+	engine := CreateEngine()
+	engine.Use(CustomRecovery(func(context *Context, err interface{}) {
+		context.WriteString(http.StatusServiceUnavailable, "recovered: %v", err)
+	}))
+	engine.GET("/panic", func(context *Context) {
+		panic("boom")
+	})
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("GET", "/panic", nil))
+	if recorder.Code != http.StatusServiceUnavailable || recorder.Body.String() != "recovered: boom" {
+		t.Fatalf("unexpected response %d %q", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestRecoveryBrokenPipe(t *testing.T) {
+	var buffer bytes.Buffer
+	engine := CreateEngine()
+	engine.Use(RecoveryWithWriter(&buffer))
+	engine.GET("/pipe", func(context *Context) {
+		panic(&net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", syscall.EPIPE)})
+	})
+
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/pipe", nil))
+	if strings.Contains(buffer.String(), "Traceback") || !strings.Contains(buffer.String(), "connection closed by client") {
+		t.Fatalf("broken pipe should not be logged as a panic: %s", buffer.String())
+	}
+}
+
+func TestRecoveryShowStack(t *testing.T) {
+	engine := CreateEngine()
+	engine.Use(RecoveryWithConfig(RecoveryConfig{Output: &bytes.Buffer{}, ShowStack: true}))
+	engine.GET("/panic", func(context *Context) {
+		panic("boom")
+	})
+
+	for mode, shown := range map[string]bool{DebugMode: true, ReleaseMode: false} {
+		SetMode(mode)
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, httptest.NewRequest("GET", "/panic", nil))
+		if strings.Contains(recorder.Body.String(), `"stack"`) != shown {
+			t.Fatalf("%s mode: unexpected body %s", mode, recorder.Body.String())
+		}
+	}
+	SetMode(DebugMode)
+}
@@ -0,0 +1,286 @@
+package dew
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type (
+	//Renderer 把数据编码为某种格式, 通过 Engine.RegisterRenderer 按 MIME 类型注册
+	Renderer interface {
+		ContentType() string
+		Render(writer io.Writer, data interface{}) error
+	}
+
+	jsonRenderer struct {
+		indent bool
+	}
+
+	xmlRenderer struct{}
+
+	yamlRenderer struct{}
+
+	textRenderer struct{}
+)
+
+//defaultSecureJsonPrefix 防止 JSON 数组被 <script> 劫持的前缀
+const defaultSecureJsonPrefix = "while(1);"
+
+//jsonpCallback 合法的 JSONP 回调名, 避免把任意脚本写进响应
+var jsonpCallback = regexp.MustCompile(`^[A-Za-z_$][\w$]*(\.[A-Za-z_$][\w$]*)*$`)
+
+func (this jsonRenderer) ContentType() string {
+	return "application/json; charset=utf-8"
+}
+
+func (this jsonRenderer) Render(writer io.Writer, data interface{}) error {
+	encoder := json.NewEncoder(writer)
+	if this.indent {
+		encoder.SetIndent("", "    ")
+	}
+	return encoder.Encode(data)
+}
+
+func (this xmlRenderer) ContentType() string {
+	return "application/xml; charset=utf-8"
+}
+
+func (this xmlRenderer) Render(writer io.Writer, data interface{}) error {
+	if value, ok := data.(H); ok {
+		data = xmlMap(value)
+	}
+	return xml.NewEncoder(writer).Encode(data)
+}
+
+func (this yamlRenderer) ContentType() string {
+	return "application/x-yaml; charset=utf-8"
+}
+
+func (this yamlRenderer) Render(writer io.Writer, data interface{}) error {
+	return encodeYAML(writer, data)
+}
+
+func (this textRenderer) ContentType() string {
+	return "text/plain; charset=utf-8"
+}
+
+func (this textRenderer) Render(writer io.Writer, data interface{}) error {
+	_, err := fmt.Fprint(writer, data)
+	return err
+}
+
+//xmlMap 让 H 可以直接输出为 <map><key>value</key></map>
+type xmlMap H
+
+func (this xmlMap) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Local: "map"}
+	if err := encoder.EncodeToken(start); nil != err {
+		return err
+	}
+	keys := make([]string, 0, len(this))
+	for key := range this {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := encoder.EncodeElement(this[key], xml.StartElement{Name: xml.Name{Local: key}}); nil != err {
+			return err
+		}
+	}
+	return encoder.EncodeToken(start.End())
+}
+
+func defaultRenderers() map[string]Renderer {
+	return map[string]Renderer{
+		"application/json":   jsonRenderer{},
+		"application/xml":    xmlRenderer{},
+		"text/xml":           xmlRenderer{},
+		"application/x-yaml": yamlRenderer{},
+		"application/yaml":   yamlRenderer{},
+		"text/yaml":          yamlRenderer{},
+		"text/plain":         textRenderer{},
+	}
+}
+
+//RegisterRenderer 注册或覆盖某个 MIME 类型的渲染器, 供 Negotiate 使用
+func (this *Engine) RegisterRenderer(mimeType string, renderer Renderer) {
+	if _, ok := this.renderers[mimeType]; !ok {
+		this.offered = append(this.offered, mimeType)
+	}
+	this.renderers[mimeType] = renderer
+}
+
+//Render 先把数据编码到缓冲区, 编码失败时仍能返回 500
+func (this *Context) Render(code int, renderer Renderer, data interface{}) {
+	this.render(code, renderer.ContentType(), renderer, data)
+}
+
+func (this *Context) render(code int, contentType string, renderer Renderer, data interface{}) {
+	var buffer bytes.Buffer
+	if err := renderer.Render(&buffer, data); nil != err {
+		this.Error(err).SetType(ErrorTypeRender)
+		http.Error(this.Writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	this.SetHeader("Content-Type", contentType)
+	this.SetCode(code)
+	this.Writer.Write(buffer.Bytes())
+}
+
+func (this *Context) WriteIndentedJson(code int, object interface{}) {
+	this.Render(code, jsonRenderer{indent: true}, object)
+}
+
+//WriteSecureJson 为数组加上前缀, 防止旧浏览器中的 JSON 劫持
+func (this *Context) WriteSecureJson(code int, object interface{}) {
+	var buffer bytes.Buffer
+	if err := (jsonRenderer{}).Render(&buffer, object); nil != err {
+		http.Error(this.Writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	this.SetHeader("Content-Type", jsonRenderer{}.ContentType())
+	this.SetCode(code)
+	if bytes.HasPrefix(buffer.Bytes(), []byte("[")) {
+		this.Writer.Write([]byte(this.engine.SecureJsonPrefix))
+	}
+	this.Writer.Write(buffer.Bytes())
+}
+
+//WriteJsonp 根据查询参数 callback 输出 JSONP, 没有回调时等同于 WriteJson
+func (this *Context) WriteJsonp(code int, object interface{}) {
+	callback := this.Query("callback")
+	if callback == "" {
+		this.WriteJson(code, object)
+		return
+	}
+	if !jsonpCallback.MatchString(callback) {
+		this.Fail(http.StatusBadRequest, "invalid jsonp callback")
+		return
+	}
+
+	var buffer bytes.Buffer
+	if err := (jsonRenderer{}).Render(&buffer, object); nil != err {
+		http.Error(this.Writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	this.SetHeader("Content-Type", "application/javascript; charset=utf-8")
+	this.SetCode(code)
+	this.Writer.Write([]byte(callback + "("))
+	this.Writer.Write(bytes.TrimRight(buffer.Bytes(), "\n"))
+	this.Writer.Write([]byte(");"))
+}
+
+func (this *Context) WriteXML(code int, object interface{}) {
+	this.Render(code, xmlRenderer{}, object)
+}
+
+func (this *Context) WriteYAML(code int, object interface{}) {
+	this.Render(code, yamlRenderer{}, object)
+}
+
+//WriteReader 以流的方式输出二进制数据, contentLength 小于 0 时不设置 Content-Length
+func (this *Context) WriteReader(code int, contentLength int64, contentType string, reader io.Reader, headers map[string]string) {
+	for key, value := range headers {
+		this.SetHeader(key, value)
+	}
+	if contentType != "" {
+		this.SetHeader("Content-Type", contentType)
+	}
+	if contentLength >= 0 {
+		this.SetHeader("Content-Length", strconv.FormatInt(contentLength, 10))
+	}
+	this.SetCode(code)
+	io.Copy(this.Writer, reader)
+}
+
+//NegotiateFormat 根据 Accept 头从 offered 中选出最合适的 MIME 类型, 都不可接受时返回空串
+func (this *Context) NegotiateFormat(offered ...string) string {
+	if len(offered) == 0 {
+		return ""
+	}
+	accepts := parseAccept(this.Request.Header.Get("Accept"))
+	if len(accepts) == 0 {
+		return offered[0]
+	}
+	for _, accept := range accepts {
+		for _, item := range offered {
+			if matchMediaType(accept, item) {
+				return item
+			}
+		}
+	}
+	return ""
+}
+
+//Negotiate 按 Accept 头选择已注册的渲染器输出 data, offered 为空时在全部渲染器中选择
+func (this *Context) Negotiate(code int, data interface{}, offered ...string) {
+	if len(offered) == 0 {
+		offered = this.engine.offered
+	}
+	format := this.NegotiateFormat(offered...)
+	renderer, ok := this.engine.renderers[format]
+	if !ok {
+		this.Fail(http.StatusNotAcceptable, "the accepted formats are not offered by the server")
+		return
+	}
+	//同一渲染器可注册在多个 MIME 类型下, 响应使用协商出的类型
+	contentType := renderer.ContentType()
+	if index := strings.IndexByte(contentType, ';'); index >= 0 {
+		format += contentType[index:]
+	}
+	this.render(code, format, renderer, data)
+}
+
+//parseAccept 返回按 q 值降序排列的媒体类型, q=0 的类型被丢弃
+func parseAccept(header string) []string {
+	type accept struct {
+		mediaType string
+		quality   float64
+	}
+	accepts := make([]accept, 0)
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(fields[0]))
+		if mediaType == "" {
+			continue
+		}
+		quality := 1.0
+		for _, field := range fields[1:] {
+			field = strings.TrimSpace(field)
+			if strings.HasPrefix(field, "q=") {
+				if value, err := strconv.ParseFloat(field[2:], 64); nil == err {
+					quality = value
+				}
+			}
+		}
+		if quality > 0 {
+			accepts = append(accepts, accept{mediaType, quality})
+		}
+	}
+	sort.SliceStable(accepts, func(i, j int) bool { return accepts[i].quality > accepts[j].quality })
+
+	mediaTypes := make([]string, len(accepts))
+	for i, item := range accepts {
+		mediaTypes[i] = item.mediaType
+	}
+	return mediaTypes
+}
+
+//matchMediaType 支持 */* 和 type/* 形式的通配
+func matchMediaType(accept, offered string) bool {
+	if accept == "*/*" || accept == "*" {
+		return true
+	}
+	if strings.HasSuffix(accept, "/*") {
+		return strings.HasPrefix(offered, accept[:len(accept)-1])
+	}
+	return accept == offered
+}
@@ -0,0 +1,120 @@
+package dew
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type renderUser struct {
+	Name  string   `json:"name"`
+	Tags  []string `json:"tags"`
+	Admin bool     `json:"admin,omitempty"`
+}
+
+func TestEncodeYAML(t *testing.T) {
+	var buffer bytes.Buffer
+	data := H{
+		"users": []renderUser{{Name: "geek", Tags: []string{"a", "true"}}},
+		"empty": H{},
+		"count": 2,
+		"note":  "key: value",
+	}
+	if err := encodeYAML(&buffer, data); nil != err {
+		t.Fatal(err)
+	}
+	expected := `count: 2
+empty: {}
+note: "key: value"
+users:
+  - name: geek
+    tags:
+      - a
+      - "true"
+`
+	if buffer.String() != expected {
+		t.Fatalf("unexpected yaml:\n%s", buffer.String())
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	engine := CreateEngine()
+	engine.GET("/user", func(context *Context) {
+		context.Negotiate(http.StatusOK, H{"name": "geek"})
+	})
+
+	cases := map[string]string{
+		"": "application/json; charset=utf-8",
+		"application/xml;q=0.9, application/x-yaml": "application/x-yaml; charset=utf-8",
+		"text/*":    "text/xml; charset=utf-8",
+		"image/png": "application/json",
+	}
+	for accept, contentType := range cases {
+		request := httptest.NewRequest("GET", "/user", nil)
+		request.Header.Set("Accept", accept)
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, request)
+		if recorder.Header().Get("Content-Type") != contentType {
+			t.Fatalf("Accept %q: unexpected Content-Type %q", accept, recorder.Header().Get("Content-Type"))
+		}
+	}
+}
+
+type csvRenderer struct{}
+
+func (this csvRenderer) ContentType() string {
+	return "text/csv"
+}
+
+func (this csvRenderer) Render(writer io.Writer, data interface{}) error {
+	_, err := io.WriteString(writer, strings.Join(data.([]string), ","))
+	return err
+}
+
+func TestRegisterRenderer(t *testing.T) {
+	engine := CreateEngine()
+	engine.RegisterRenderer("text/csv", csvRenderer{})
+	engine.GET("/list", func(context *Context) {
+		context.Negotiate(http.StatusOK, []string{"a", "b"}, "text/csv")
+	})
+
+	request := httptest.NewRequest("GET", "/list", nil)
+	request.Header.Set("Accept", "text/csv")
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+	if recorder.Body.String() != "a,b" {
+		t.Fatalf("unexpected body %q", recorder.Body.String())
+	}
+}
+
+func TestJsonVariants(t *testing.T) {
+	engine := CreateEngine()
+	engine.GET("/jsonp", func(context *Context) {
+		context.WriteJsonp(http.StatusOK, H{"a": 1})
+	})
+	engine.GET("/secure", func(context *Context) {
+		context.WriteSecureJson(http.StatusOK, []int{1, 2})
+	})
+
+	cases := map[string]string{
+		"/jsonp?callback=app.done": `app.done({"a":1});`,
+		"/jsonp":                   "{\"a\":1}\n",
+		"/secure":                  "while(1);[1,2]\n",
+	}
+	for path, body := range cases {
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+		if recorder.Body.String() != body {
+			t.Fatalf("%s: unexpected body %q", path, recorder.Body.String())
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("GET", "/jsonp?callback=alert(1)", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("invalid callback should be rejected, got %d", recorder.Code)
+	}
+}
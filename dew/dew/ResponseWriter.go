@@ -0,0 +1,113 @@
+package dew
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+)
+
+//noWritten 响应头尚未写出时 size 的取值
+const noWritten = -1
+
+type (
+	//ResponseWriter 记录状态码, 响应体大小以及响应头是否已写出
+	//WriteHeader 只记录状态码, 真正的写出推迟到第一次 Write 或 WriteHeaderNow
+	ResponseWriter interface {
+		http.ResponseWriter
+		http.Hijacker
+		http.Flusher
+		//Status 返回已设置的状态码
+		Status() int
+		//Size 返回已写出的响应体字节数, 未写出响应头时为 -1
+		Size() int
+		//Written 响应头是否已写出
+		Written() bool
+		//WriteHeaderNow 立即写出响应头
+		WriteHeaderNow()
+		WriteString(string) (int, error)
+	}
+
+	responseWriter struct {
+		http.ResponseWriter
+		size   int
+		status int
+	}
+)
+
+func (this *responseWriter) reset(writer http.ResponseWriter) {
+	this.ResponseWriter = writer
+	this.size = noWritten
+	this.status = http.StatusOK
+}
+
+func (this *responseWriter) WriteHeader(code int) {
+	if code <= 0 || this.status == code {
+		return
+	}
+	if this.Written() {
+		log.Printf("[WARNING] headers were already written, wanted to override status code %d with %d", this.status, code)
+		return
+	}
+	this.status = code
+}
+
+func (this *responseWriter) WriteHeaderNow() {
+	if !this.Written() {
+		this.size = 0
+		this.ResponseWriter.WriteHeader(this.status)
+	}
+}
+
+func (this *responseWriter) Write(data []byte) (int, error) {
+	this.WriteHeaderNow()
+	n, err := this.ResponseWriter.Write(data)
+	this.size += n
+	return n, err
+}
+
+func (this *responseWriter) WriteString(s string) (int, error) {
+	this.WriteHeaderNow()
+	n, err := io.WriteString(this.ResponseWriter, s)
+	this.size += n
+	return n, err
+}
+
+func (this *responseWriter) Status() int {
+	return this.status
+}
+
+func (this *responseWriter) Size() int {
+	return this.size
+}
+
+func (this *responseWriter) Written() bool {
+	return this.size != noWritten
+}
+
+//Hijack 接管连接后不再由 net/http 写出响应
+func (this *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := this.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("dew: the ResponseWriter does not implement http.Hijacker")
+	}
+	conn, buffered, err := hijacker.Hijack()
+	if nil == err && this.size < 0 {
+		this.size = 0
+	}
+	return conn, buffered, err
+}
+
+func (this *responseWriter) Flush() {
+	this.WriteHeaderNow()
+	if flusher, ok := this.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+//Unwrap 供 http.ResponseController 访问底层的 ResponseWriter
+func (this *responseWriter) Unwrap() http.ResponseWriter {
+	return this.ResponseWriter
+}
@@ -2,18 +2,18 @@ package dew
 
 import (
 	"net/http"
+	"sort"
 	"strings"
 )
 
 type router struct {
-	roots    map[string]*node
-	handlers map[string]HandlerFunction
+	roots     map[string]*node
+	maxParams int //所有路由中参数的最大个数, 用于预分配 Params
 }
 
 func createRouter() *router {
 	return &router{
-		roots:    make(map[string]*node),
-		handlers: make(map[string]HandlerFunction),
+		roots: make(map[string]*node),
 	}
 }
 
@@ -31,43 +31,56 @@ func parsePattern(pattern string) []string {
 	return parts
 }
 
-func (this *router) addRoute(method, pattern string, handler HandlerFunction) {
+//addRoute 注册路由并返回对应节点, 处理链由 Engine 负责计算
+func (this *router) addRoute(method, pattern string, handlers []HandlerFunction) *node {
 	parts := parsePattern(pattern)
+	//parsePattern 会截断 *catchall 之后的部分, 段数不一致说明 catch-all 不在末尾
+	if segments := strings.FieldsFunc(pattern, func(r rune) bool { return r == '/' }); len(segments) != len(parts) {
+		panic("dew: catch-all must be at the end of route '" + pattern + "'")
+	}
+	//统一为不带结尾 '/' 的形式, 重复的 '/' 也被合并
+	pattern = "/" + strings.Join(parts, "/")
+
+	count := 0
+	for _, part := range parts {
+		if part[0] == ':' || part[0] == '*' {
+			count++
+		}
+	}
+	if count > this.maxParams {
+		this.maxParams = count
+	}
 
-	key := method + "-" + pattern
 	_, ok := this.roots[method]
 	if !ok {
 		this.roots[method] = &node{}
 	}
-	this.roots[method].insert(pattern, parts, 0)
-	this.handlers[key] = handler
+	n := this.roots[method].insert(pattern, pattern)
+	n.handlers = handlers
+	n.chain = handlers
+	return n
 }
 
-func (this *router) getRoute(method, path string) (*node, map[string]string) {
-	searchParts := parsePattern(path)
-	params := make(map[string]string)
+//find 查找路由, 参数追加到 params 中, 调用方复用 params 时不分配内存
+func (this *router) find(method, path string, params *Params) *node {
 	root, ok := this.roots[method]
-
 	if !ok {
-		return nil, nil
+		return nil
 	}
+	return root.search(path, params)
+}
 
-	n := root.search(searchParts, 0)
-	if nil != n {
-		parts := parsePattern(n.pattern)
-		for index, part := range parts {
-			if part[0] == ':' {
-				params[part[1:]] = searchParts[index]
-			}
-
-			if part[0] == '*' && len(part) > 1 {
-				params[part[1:]] = strings.Join(searchParts[index:], "/")
-				break
-			}
-		}
-		return n, params
+//getRoute 查找路由, 参数写入 params[:0] 并返回, params 容量足够时不分配内存, 传 nil 时按需分配
+func (this *router) getRoute(method, path string, params Params) (*node, Params) {
+	if nil == params {
+		params = make(Params, 0, this.maxParams)
+	}
+	params = params[:0]
+	n := this.find(method, path, &params)
+	if nil == n {
+		return nil, nil
 	}
-	return nil, nil
+	return n, params
 }
 
 func (this *router) getRouters(method string) []*node {
@@ -76,20 +89,76 @@ func (this *router) getRouters(method string) []*node {
 		return nil
 	}
 	nodes := make([]*node, 0)
-	root.travel(nodes)
+	root.travel(&nodes)
 	return nodes
 }
 
+//allowed 返回所有能匹配 path 的请求方法, GET 隐含 HEAD, 任意匹配隐含 OPTIONS
+func (this *router) allowed(path string) []string {
+	methods := make([]string, 0, len(this.roots)+2)
+	params := make(Params, 0, this.maxParams)
+	for method := range this.roots {
+		if n, found := this.getRoute(method, path, params); nil != n {
+			params = found
+			methods = append(methods, method)
+		}
+	}
+	if len(methods) == 0 {
+		return nil
+	}
+
+	hasMethod := func(method string) bool {
+		for _, item := range methods {
+			if item == method {
+				return true
+			}
+		}
+		return false
+	}
+	if hasMethod(http.MethodGet) && !hasMethod(http.MethodHead) {
+		methods = append(methods, http.MethodHead)
+	}
+	if !hasMethod(http.MethodOptions) {
+		methods = append(methods, http.MethodOptions)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
 func (this *router) handle(context *Context) {
-	n, params := this.getRoute(context.Method, context.Path)
+	method := context.Method
+	if cap(context.Params) < this.maxParams {
+		context.Params = make(Params, 0, this.maxParams)
+	}
+	n := this.find(method, context.Path, &context.Params)
+	//HEAD 请求没有注册时复用 GET 的处理器, 响应体由 net/http 丢弃
+	if nil == n && method == http.MethodHead {
+		method = http.MethodGet
+		n = this.find(method, context.Path, &context.Params)
+	}
+
 	if n != nil {
-		key := context.Method + "-" + n.pattern
-		context.Params = params
-		context.handlers = append(context.handlers, this.handlers[key])
-	} else {
-		context.handlers = append(context.handlers, func(context *Context) {
-			context.WriteString(http.StatusNotFound, "404 NOT FOUND: %s\n", context.Path)
+		context.handlers = n.chain
+		context.Next()
+		return
+	}
+
+	//未命中时才按请求路径收集分组中间件
+	handlers := context.engine.matchMiddlewares(context.Path)
+	allowed := this.allowed(context.Path)
+	switch {
+	case len(allowed) == 0:
+		handlers = append(handlers, context.engine.noRoute...)
+	case method == http.MethodOptions:
+		handlers = append(handlers, func(context *Context) {
+			context.SetHeader("Allow", strings.Join(allowed, ", "))
+			context.SetCode(http.StatusNoContent)
 		})
+	default:
+		//路径存在于其他方法下, 按 RFC 7231 返回 405 并给出 Allow
+		context.SetHeader("Allow", strings.Join(allowed, ", "))
+		handlers = append(handlers, context.engine.noMethod...)
 	}
+	context.handlers = handlers
 	context.Next()
 }
@@ -3,7 +3,7 @@ package dew
 import (
 	"log"
 	"net/http"
-	"path"
+	"strings"
 )
 
 //分组
@@ -25,43 +25,81 @@ func (this *RouterGroup) Group(prefix string) *RouterGroup {
 	return group
 }
 
-func (this *RouterGroup) addRoute(method, comp string, handler HandlerFunction) {
+//anyMethods Any 注册时覆盖的请求方法
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodHead, http.MethodOptions, http.MethodDelete, http.MethodConnect,
+	http.MethodTrace,
+}
+
+//hasPathPrefix 按路径段匹配前缀, "/v1" 匹配 "/v1" 和 "/v1/x", 不匹配 "/v10"
+func hasPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || path[len(prefix)] == '/'
+}
+
+//match 按路径段判断 path 是否属于该分组
+func (this *RouterGroup) match(path string) bool {
+	return hasPathPrefix(path, this.prefix)
+}
+
+func (this *RouterGroup) addRoute(method, comp string, handlers []HandlerFunction) {
 	pattern := this.prefix + comp
+	if len(handlers) == 0 {
+		panic("dew: route '" + pattern + "' has no handlers")
+	}
 	log.Printf("Route %4s - %4s", method, pattern)
-	this.engine.router.addRoute(method, pattern, handler)
+	//复制一份, 避免调用方之后修改切片影响已注册的路由
+	chain := make([]HandlerFunction, len(handlers))
+	copy(chain, handlers)
+	n := this.engine.router.addRoute(method, pattern, chain)
+	n.chain = this.engine.combineHandlers(n.pattern, n.handlers)
 }
 
 func (this *RouterGroup) Use(middlewares ...HandlerFunction) {
 	this.middlewares = append(this.middlewares, middlewares...)
+	this.engine.rebuildChains()
 }
 
-func (this *RouterGroup) GET(pattern string, handler HandlerFunction) {
-	this.addRoute("GET", pattern, handler)
+//Handle 以任意请求方法注册路由, handlers 依次执行
+func (this *RouterGroup) Handle(method, pattern string, handlers ...HandlerFunction) {
+	this.addRoute(method, pattern, handlers)
 }
 
-func (this *RouterGroup) POST(pattern string, handler HandlerFunction) {
-	this.addRoute("POST", pattern, handler)
+func (this *RouterGroup) GET(pattern string, handlers ...HandlerFunction) {
+	this.addRoute(http.MethodGet, pattern, handlers)
 }
 
-//createStaticHandler 创建静态文件处理器
-func (this *RouterGroup) createStaticHandler(relativePath string, fs http.FileSystem) HandlerFunction {
-	absolutePath := path.Join(this.prefix, relativePath)
-	fileServer := http.StripPrefix(absolutePath, http.FileServer(fs))
-	return func(context *Context) {
-		file := context.Param("filepath")
-		//检查文件是否存在和/或我们是否有权限访问它
-		if _, err := fs.Open(file); nil != err {
-			context.SetCode(http.StatusNotFound)
-			return
-		}
-		fileServer.ServeHTTP(context.Writer, context.Request)
-	}
+func (this *RouterGroup) POST(pattern string, handlers ...HandlerFunction) {
+	this.addRoute(http.MethodPost, pattern, handlers)
+}
+
+func (this *RouterGroup) PUT(pattern string, handlers ...HandlerFunction) {
+	this.addRoute(http.MethodPut, pattern, handlers)
+}
+
+func (this *RouterGroup) PATCH(pattern string, handlers ...HandlerFunction) {
+	this.addRoute(http.MethodPatch, pattern, handlers)
+}
+
+func (this *RouterGroup) DELETE(pattern string, handlers ...HandlerFunction) {
+	this.addRoute(http.MethodDelete, pattern, handlers)
 }
 
-//Static 静态文件服务
-func (this *RouterGroup) Static(relativePath, root string) {
-	handler := this.createStaticHandler(relativePath, http.Dir(root))
-	urlPattern := path.Join(relativePath, "/*filepath")
-	//注册 GET 处理器
-	this.GET(urlPattern, handler)
+func (this *RouterGroup) HEAD(pattern string, handlers ...HandlerFunction) {
+	this.addRoute(http.MethodHead, pattern, handlers)
+}
+
+func (this *RouterGroup) OPTIONS(pattern string, handlers ...HandlerFunction) {
+	this.addRoute(http.MethodOptions, pattern, handlers)
+}
+
+//Any 为所有常用请求方法注册同一组处理器
+func (this *RouterGroup) Any(pattern string, handlers ...HandlerFunction) {
+	for _, method := range anyMethods {
+		this.addRoute(method, pattern, handlers)
+	}
 }
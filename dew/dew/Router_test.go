@@ -2,6 +2,8 @@ package dew
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 )
@@ -27,7 +29,7 @@ func TestParsePattern(t *testing.T) {
 
 func TestGetRoute(t *testing.T) {
 	r := newTestRouter()
-	n, ps := r.getRoute("GET", "/hello/geektutu")
+	n, ps := r.getRoute("GET", "/hello/geektutu", nil)
 
 	if n == nil {
 		t.Fatal("nil shouldn't be returned")
@@ -37,10 +39,278 @@ func TestGetRoute(t *testing.T) {
 		t.Fatal("should match /hello/:name")
 	}
 
-	if ps["name"] != "geektutu" {
+	if ps.ByName("name") != "geektutu" {
 		t.Fatal("name should be equal to 'geektutu'")
 	}
 
-	fmt.Printf("matched path: %s, params['name']: %s\n", n.pattern, ps["name"])
+	fmt.Printf("matched path: %s, params['name']: %s\n", n.pattern, ps.ByName("name"))
 
 }
+
+func TestAllowed(t *testing.T) {
+	r := newTestRouter()
+	r.addRoute("POST", "/hello/:name", nil)
+
+	allowed := r.allowed("/hello/geektutu")
+	if !reflect.DeepEqual(allowed, []string{"GET", "HEAD", "OPTIONS", "POST"}) {
+		t.Fatalf("unexpected allowed methods %v", allowed)
+	}
+	if r.allowed("/nothing/here") != nil {
+		t.Fatal("unknown path should have no allowed methods")
+	}
+}
+
+func TestHeadAndOptions(t *testing.T) {
+	engine := CreateEngine()
+	engine.GET("/hello", func(context *Context) {
+		context.WriteString(http.StatusOK, "hello")
+	})
+	engine.Any("/any", func(context *Context) {
+		context.WriteString(http.StatusOK, context.Method)
+	})
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("HEAD", "/hello", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("HEAD should fall back to GET, got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("OPTIONS", "/hello", nil))
+	if recorder.Code != http.StatusNoContent || recorder.Header().Get("Allow") != "GET, HEAD, OPTIONS" {
+		t.Fatalf("unexpected OPTIONS response %d %q", recorder.Code, recorder.Header().Get("Allow"))
+	}
+
+	recorder = httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("PATCH", "/any", nil))
+	if recorder.Body.String() != "PATCH" {
+		t.Fatalf("Any should handle PATCH, got %q", recorder.Body.String())
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	engine := CreateEngine()
+	engine.POST("/login", func(context *Context) {})
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("GET", "/login", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", recorder.Code)
+	}
+	if recorder.Header().Get("Allow") != "OPTIONS, POST" {
+		t.Fatalf("unexpected Allow header %q", recorder.Header().Get("Allow"))
+	}
+}
+
+func TestNoRouteAndNoMethod(t *testing.T) {
+	engine := CreateEngine()
+	engine.Use(func(context *Context) {
+		context.SetHeader("X-Middleware", "yes")
+		context.Next()
+	})
+	engine.POST("/login", func(context *Context) {})
+	engine.NoRoute(func(context *Context) {
+		context.WriteJson(http.StatusNotFound, H{"message": "no route"})
+	})
+	engine.NoMethod(func(context *Context) {
+		context.WriteJson(http.StatusMethodNotAllowed, H{"message": "no method"})
+	})
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("GET", "/missing", nil))
+	if recorder.Code != http.StatusNotFound || recorder.Header().Get("X-Middleware") != "yes" {
+		t.Fatalf("NoRoute should run after middlewares, got %d", recorder.Code)
+	}
+	if recorder.Body.String() != "{\"message\":\"no route\"}\n" {
+		t.Fatalf("unexpected NoRoute body %q", recorder.Body.String())
+	}
+
+	recorder = httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("DELETE", "/login", nil))
+	if recorder.Code != http.StatusMethodNotAllowed || recorder.Header().Get("Allow") != "OPTIONS, POST" {
+		t.Fatalf("NoMethod should answer 405 with Allow, got %d", recorder.Code)
+	}
+}
+
+func TestRoutePriority(t *testing.T) {
+	r := createRouter()
+	r.addRoute("GET", "/src/*filepath", nil)
+	r.addRoute("GET", "/src/:name/info", nil)
+	r.addRoute("GET", "/src/static/info", nil)
+	r.addRoute("GET", "/src/staff", nil)
+	r.addRoute("GET", "/v1/", nil)
+
+	cases := []struct {
+		path    string
+		pattern string
+		params  Params
+	}{
+		{"/src/static/info", "/src/static/info", Params{}},
+		{"/src/staff", "/src/staff", Params{}},
+		{"/src/stat/info", "/src/:name/info", Params{{"name", "stat"}}},
+		{"/src/static", "/src/*filepath", Params{{"filepath", "static"}}},
+		{"/src/a/b/c", "/src/*filepath", Params{{"filepath", "a/b/c"}}},
+		{"/src/", "/src/*filepath", Params{{"filepath", ""}}},
+		{"/v1", "/v1", Params{}},
+		{"/v1/", "/v1", Params{}},
+	}
+	for _, item := range cases {
+		n, ps := r.getRoute("GET", item.path, nil)
+		if nil == n || n.pattern != item.pattern {
+			t.Fatalf("%s should match %s, got %v", item.path, item.pattern, n)
+		}
+		if !reflect.DeepEqual(ps, item.params) {
+			t.Fatalf("%s: unexpected params %v", item.path, ps)
+		}
+	}
+
+	if n, _ := r.getRoute("GET", "/src", nil); nil != n {
+		t.Fatalf("/src should not match, got %s", n.pattern)
+	}
+}
+
+func TestRouteConflicts(t *testing.T) {
+	conflicts := [][]string{
+		{"/hello/:name", "/hello/:id"},
+		{"/hello/:name", "/hello/:name"},
+		{"/v1", "/v1/"},
+		{"/assets/*filepath", "/assets/*path"},
+		{"/assets/*filepath/more"},
+		{"/hello/:"},
+		{"/hello/:a:b"},
+	}
+	for _, patterns := range conflicts {
+		func() {
+			defer func() {
+				if nil == recover() {
+					t.Fatalf("%v should panic", patterns)
+				}
+			}()
+			r := createRouter()
+			for _, pattern := range patterns {
+				r.addRoute("GET", pattern, nil)
+			}
+		}()
+	}
+}
+
+func TestSearchAllocations(t *testing.T) {
+	r := newTestRouter()
+	root := r.roots["GET"]
+	params := make(Params, 0, r.maxParams)
+	allocs := testing.AllocsPerRun(100, func() {
+		params = params[:0]
+		root.search("/hello/geektutu", &params)
+		params = params[:0]
+		root.search("/assets/css/geektutu.css", &params)
+	})
+	if allocs != 0 {
+		t.Fatalf("search should not allocate, got %v allocs", allocs)
+	}
+
+	//getRoute 复用调用方的缓冲区时同样不分配内存
+	allocs = testing.AllocsPerRun(100, func() {
+		_, params = r.getRoute("GET", "/hello/geektutu", params)
+		_, params = r.getRoute("GET", "/assets/css/geektutu.css", params)
+	})
+	if allocs != 0 {
+		t.Fatalf("getRoute should not allocate with a reused buffer, got %v allocs", allocs)
+	}
+}
+
+func TestRouteHandlerChain(t *testing.T) {
+	engine := CreateEngine()
+	v1 := engine.Group("/v1")
+	v1.Use(func(context *Context) {
+		context.SetHeader("X-Group", "v1")
+		context.Next()
+	})
+	auth := func(context *Context) {
+		if context.Query("token") == "" {
+			context.Fail(http.StatusUnauthorized, "unauthorized")
+		}
+	}
+	v1.GET("/secret", auth, func(context *Context) {
+		context.WriteString(http.StatusOK, "secret")
+	})
+	engine.GET("/v10/hello", func(context *Context) {
+		context.WriteString(http.StatusOK, "hello")
+	})
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("GET", "/v1/secret", nil))
+	if recorder.Code != http.StatusUnauthorized || recorder.Header().Get("X-Group") != "v1" {
+		t.Fatalf("route middleware should abort the chain, got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("GET", "/v1/secret?token=1", nil))
+	if recorder.Body.String() != "secret" {
+		t.Fatalf("unexpected body %q", recorder.Body.String())
+	}
+
+	recorder = httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("GET", "/v10/hello", nil))
+	if recorder.Header().Get("X-Group") != "" {
+		t.Fatal("/v1 middleware should not run on /v10")
+	}
+}
+
+func TestHandleContextInHandler(t *testing.T) {
+	engine := CreateEngine()
+	var after string
+	engine.GET("/a/:name", func(context *Context) {
+		context.Set("user", "alice")
+		context.Next()
+		after = context.Path + " " + context.Param("name")
+	}, func(context *Context) {
+		context.Request.URL.Path = "/b"
+		engine.HandleContext(context)
+	}, func(context *Context) {
+		t.Fatal("handlers after HandleContext should not run")
+	})
+	engine.GET("/b", func(context *Context) {
+		context.WriteString(http.StatusOK, "b %s", context.MustGet("user"))
+	})
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("GET", "/a/x", nil))
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "b alice" {
+		t.Fatalf("unexpected response %d %q", recorder.Code, recorder.Body.String())
+	}
+	if after != "/a/x x" {
+		t.Fatalf("outer handlers should see the original route, got %q", after)
+	}
+}
+
+func TestGetRouters(t *testing.T) {
+	r := newTestRouter()
+	if nodes := r.getRouters("GET"); len(nodes) != 5 {
+		t.Fatalf("expected 5 routes, got %d", len(nodes))
+	}
+	if nodes := r.getRouters("POST"); nodes != nil {
+		t.Fatal("unknown method should have no routes")
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	engine := CreateEngine()
+	engine.Use(Logger())
+	v1 := engine.Group("/v1")
+	v1.Use(Recovery())
+	v1.POST("/login", LoggerWithConfig(LoggerConfig{}), handlerForTest)
+	engine.GET("/", handlerForTest)
+	engine.GET("/named", NameHandler("shop.Audit", func(context *Context) {}), handlerForTest)
+
+	expected := RoutesInfo{
+		{Method: "GET", Path: "/", Handler: "dew/dew.handlerForTest", Middlewares: []string{"dew/dew.Logger"}},
+		{Method: "GET", Path: "/named", Handler: "dew/dew.handlerForTest", Middlewares: []string{"dew/dew.Logger", "shop.Audit"}},
+		{Method: "POST", Path: "/v1/login", Handler: "dew/dew.handlerForTest", Middlewares: []string{"dew/dew.Logger", "dew/dew.Recovery", "dew/dew.Logger"}},
+	}
+	routes := engine.Routes()
+	if !reflect.DeepEqual(routes, expected) {
+		t.Fatalf("unexpected routes %+v", routes)
+	}
+}
+
+func handlerForTest(context *Context) {}
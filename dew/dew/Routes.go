@@ -0,0 +1,95 @@
+package dew
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+type (
+	//RouteInfo 已注册路由的描述
+	RouteInfo struct {
+		Method      string   `json:"method"`
+		Path        string   `json:"path"`
+		Handler     string   `json:"handler"`
+		Middlewares []string `json:"middlewares"`
+	}
+
+	RoutesInfo []RouteInfo
+)
+
+//handlerNames 按函数入口地址登记的名称, 同一个函数字面量创建的闭包入口相同
+var handlerNames sync.Map
+
+//NameHandler 登记 handler 在 Routes 中显示的名称并原样返回
+//中间件构造函数返回的闭包默认显示为 xxx.func1, 登记后不随配置方式或代码位置变化
+func NameHandler(name string, handler HandlerFunction) HandlerFunction {
+	handlerNames.Store(reflect.ValueOf(handler).Pointer(), name)
+	return handler
+}
+
+//nameOfFunction 返回登记的名称或函数的完整名称, 例如 dew/dew.Logger
+func nameOfFunction(function interface{}) string {
+	value := reflect.ValueOf(function)
+	if value.Kind() != reflect.Func || value.IsNil() {
+		return ""
+	}
+	if name, ok := handlerNames.Load(value.Pointer()); ok {
+		return name.(string)
+	}
+	return runtime.FuncForPC(value.Pointer()).Name()
+}
+
+//Routes 返回所有已注册的路由, 按路径和方法排序
+func (this *Engine) Routes() RoutesInfo {
+	routes := make(RoutesInfo, 0)
+	for method := range this.router.roots {
+		for _, n := range this.router.getRouters(method) {
+			info := RouteInfo{Method: method, Path: n.pattern, Middlewares: make([]string, 0)}
+			if len(n.chain) > 0 {
+				for _, handler := range n.chain[:len(n.chain)-1] {
+					info.Middlewares = append(info.Middlewares, nameOfFunction(handler))
+				}
+				info.Handler = nameOfFunction(n.chain[len(n.chain)-1])
+			}
+			routes = append(routes, info)
+		}
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+//PrintRoutes 以表格形式输出路由表, 便于在 CI 中审计
+func (this *Engine) PrintRoutes(writer io.Writer) error {
+	table := tabwriter.NewWriter(writer, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "METHOD\tPATH\tHANDLER\tMIDDLEWARES")
+	for _, route := range this.Routes() {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", route.Method, route.Path, route.Handler, strings.Join(route.Middlewares, ", "))
+	}
+	return table.Flush()
+}
+
+//RoutesHandler 调试用的路由表接口, ?format=text 时输出表格, 否则输出 JSON
+func (this *Engine) RoutesHandler() HandlerFunction {
+	return func(context *Context) {
+		if context.Query("format") == "text" {
+			context.SetHeader("Content-Type", "text/plain")
+			context.SetCode(http.StatusOK)
+			this.PrintRoutes(context.Writer)
+			return
+		}
+		context.WriteJson(http.StatusOK, this.Routes())
+	}
+}
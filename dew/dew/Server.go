@@ -0,0 +1,127 @@
+package dew
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+//newServer 按 Engine 上的超时配置创建 http.Server, 并记录下来供 Shutdown 使用
+//已经调用过 Shutdown 时返回 http.ErrServerClosed
+func (this *Engine) newServer(addr string) (*http.Server, error) {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           this,
+		ReadTimeout:       this.ReadTimeout,
+		ReadHeaderTimeout: this.ReadHeaderTimeout,
+		WriteTimeout:      this.WriteTimeout,
+		IdleTimeout:       this.IdleTimeout,
+		MaxHeaderBytes:    this.MaxHeaderBytes,
+	}
+	this.serversMutex.Lock()
+	defer this.serversMutex.Unlock()
+	if this.shuttingDown {
+		return nil, http.ErrServerClosed
+	}
+	this.servers = append(this.servers, server)
+	return server, nil
+}
+
+//serve 服务因 Shutdown 停止时等待在途请求处理完毕再返回 nil
+func (this *Engine) serve(serve func() error) error {
+	err := serve()
+	if err == http.ErrServerClosed {
+		<-this.shutdownDone
+		return nil
+	}
+	return err
+}
+
+//Run 定义了启动http服务器的方法
+func (this *Engine) Run(host string) error {
+	server, err := this.newServer(host)
+	if nil != err {
+		return err
+	}
+	return this.serve(server.ListenAndServe)
+}
+
+//RunTLS 以 HTTPS 方式启动服务器
+func (this *Engine) RunTLS(host, certFile, keyFile string) error {
+	server, err := this.newServer(host)
+	if nil != err {
+		return err
+	}
+	return this.serve(func() error {
+		return server.ListenAndServeTLS(certFile, keyFile)
+	})
+}
+
+//RunUnix 在 unix socket 上启动服务器, 已存在的 socket 文件会被删除
+func (this *Engine) RunUnix(file string) error {
+	os.Remove(file)
+	listener, err := net.Listen("unix", file)
+	if nil != err {
+		return err
+	}
+	defer os.Remove(file)
+	return this.RunListener(listener)
+}
+
+//RunListener 在给定的 listener 上启动服务器, 便于使用 systemd socket 或测试
+func (this *Engine) RunListener(listener net.Listener) error {
+	server, err := this.newServer(listener.Addr().String())
+	if nil != err {
+		listener.Close()
+		return err
+	}
+	return this.serve(func() error {
+		return server.Serve(listener)
+	})
+}
+
+//Shutdown 停止接受新连接并等待在途请求结束, ctx 到期时返回其错误
+//之后再调用 Run 系列方法会直接返回 http.ErrServerClosed
+func (this *Engine) Shutdown(ctx context.Context) error {
+	this.serversMutex.Lock()
+	this.shuttingDown = true
+	servers := this.servers
+	this.serversMutex.Unlock()
+
+	var result error
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); nil != err && nil == result {
+			result = err
+		}
+	}
+	this.shutdownOnce.Do(func() {
+		close(this.shutdownDone)
+	})
+	return result
+}
+
+//ShutdownOnSignal 收到信号后在 timeout 内优雅关闭, 默认监听 SIGINT 和 SIGTERM
+func (this *Engine) ShutdownOnSignal(timeout time.Duration, signals ...os.Signal) {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, signals...)
+
+	go func() {
+		received := <-quit
+		signal.Stop(quit)
+		log.Printf("Received %s, shutting down within %v", received, timeout)
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := this.Shutdown(ctx); nil != err {
+			log.Printf("Shutdown: %v", err)
+		}
+	}()
+}
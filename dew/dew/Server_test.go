@@ -0,0 +1,82 @@
+package dew
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestGracefulShutdown(t *testing.T) {
+	engine := CreateEngine()
+	engine.ReadTimeout = time.Second
+	started := make(chan struct{})
+	engine.GET("/slow", func(context *Context) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		context.WriteString(http.StatusOK, "done")
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	result := make(chan error, 1)
+	go func() {
+		result <- engine.RunListener(listener)
+	}()
+
+	body := make(chan string, 1)
+	go func() {
+		response, err := http.Get("http://" + listener.Addr().String() + "/slow")
+		if nil != err {
+			body <- err.Error()
+			return
+		}
+		defer response.Body.Close()
+		data, _ := ioutil.ReadAll(response.Body)
+		body <- string(data)
+	}()
+
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := engine.Shutdown(ctx); nil != err {
+		t.Fatal(err)
+	}
+	if err := <-result; nil != err {
+		t.Fatalf("RunListener should return nil after Shutdown, got %v", err)
+	}
+	if got := <-body; got != "done" {
+		t.Fatalf("in-flight request should be drained, got %q", got)
+	}
+}
+
+func TestShutdownBeforeRun(t *testing.T) {
+	engine := CreateEngine()
+	if err := engine.Shutdown(context.Background()); nil != err {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	result := make(chan error, 1)
+	go func() {
+		result <- engine.RunListener(listener)
+	}()
+	select {
+	case err := <-result:
+		if err != http.ErrServerClosed {
+			t.Fatalf("RunListener after Shutdown should return ErrServerClosed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("RunListener after Shutdown should not start serving")
+	}
+	if _, err := net.Dial("tcp", listener.Addr().String()); nil == err {
+		t.Fatal("listener should be closed")
+	}
+}
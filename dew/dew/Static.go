@@ -0,0 +1,199 @@
+package dew
+
+import (
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//StaticConfig 静态文件服务配置, 零值表示不列目录, 不设置缓存头
+type StaticConfig struct {
+	//Browse 目录中没有 index.html 时是否列出文件
+	Browse bool
+	//MaxAge 大于 0 时设置 Cache-Control: public, max-age
+	MaxAge time.Duration
+	//Immutable 在 Cache-Control 中加上 immutable, 适用于文件名带哈希的资源
+	Immutable bool
+	//ETag 按修改时间和大小生成弱 ETag, 配合 If-None-Match 返回 304
+	ETag bool
+	//Precompressed 客户端接受 gzip 且存在同名 .gz 文件时直接发送该文件
+	Precompressed bool
+	//SPA 不存在且没有扩展名的路径返回 Index, 交给前端路由处理
+	SPA bool
+	//Index 目录和 SPA 使用的首页, 默认 index.html
+	Index string
+}
+
+//defaultStaticConfig Static 和 StaticFS 使用的配置
+var defaultStaticConfig = StaticConfig{ETag: true}
+
+//notFound 用 NoRoute 处理器替换剩余的处理器并继续执行, 与未匹配路由时的响应保持一致
+func (this *Context) notFound() {
+	handlers := make([]HandlerFunction, 0, this.index+1+len(this.engine.noRoute))
+	handlers = append(handlers, this.handlers[:this.index+1]...)
+	this.handlers = append(handlers, this.engine.noRoute...)
+	this.Next()
+}
+
+func staticETag(info os.FileInfo, suffix string) string {
+	return `W/"` + strconv.FormatInt(info.ModTime().UnixNano(), 16) + "-" +
+		strconv.FormatInt(info.Size(), 16) + suffix + `"`
+}
+
+//openStatic 打开文件并返回其信息, 出错时文件已关闭
+func openStatic(fileSystem http.FileSystem, name string) (http.File, os.FileInfo, error) {
+	file, err := fileSystem.Open(name)
+	if nil != err {
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if nil != err {
+		file.Close()
+		return nil, nil, err
+	}
+	return file, info, nil
+}
+
+//serveStatic 发送已打开的普通文件, 由 http.ServeContent 处理 Range 和条件请求
+func (this *StaticConfig) serveStatic(context *Context, fileSystem http.FileSystem, name string, file http.File, info os.FileInfo) {
+	header := context.Writer.Header()
+	if this.MaxAge > 0 {
+		cacheControl := "public, max-age=" + strconv.FormatInt(int64(this.MaxAge/time.Second), 10)
+		if this.Immutable {
+			cacheControl += ", immutable"
+		}
+		header.Set("Cache-Control", cacheControl)
+	}
+
+	if this.Precompressed {
+		header.Add("Vary", "Accept-Encoding")
+		if negotiateEncoding(context.Request.Header.Get("Accept-Encoding")) == "gzip" {
+			compressed, compressedInfo, err := openStatic(fileSystem, name+".gz")
+			if nil == err && !compressedInfo.IsDir() {
+				defer compressed.Close()
+				contentType := mime.TypeByExtension(path.Ext(name))
+				if contentType == "" {
+					contentType = "application/octet-stream"
+				}
+				header.Set("Content-Type", contentType)
+				header.Set("Content-Encoding", "gzip")
+				if this.ETag {
+					header.Set("ETag", staticETag(compressedInfo, "-gzip"))
+				}
+				http.ServeContent(context.Writer, context.Request, info.Name(), compressedInfo.ModTime(), compressed)
+				return
+			}
+			if nil == err {
+				compressed.Close()
+			}
+		}
+	}
+
+	if this.ETag {
+		header.Set("ETag", staticETag(info, ""))
+	}
+	http.ServeContent(context.Writer, context.Request, info.Name(), info.ModTime(), file)
+}
+
+//createStaticHandler 创建静态文件处理器, 文件不存在时交给 NoRoute 处理器
+func (this *RouterGroup) createStaticHandler(relativePath string, fileSystem http.FileSystem, config StaticConfig) HandlerFunction {
+	if config.Index == "" {
+		config.Index = "index.html"
+	}
+	absolutePath := path.Join(this.prefix, relativePath)
+	fileServer := http.StripPrefix(absolutePath, http.FileServer(fileSystem))
+
+	return func(context *Context) {
+		name := path.Clean("/" + context.Param("filepath"))
+		file, info, err := openStatic(fileSystem, name)
+		if nil != err {
+			if config.SPA && path.Ext(name) == "" {
+				name = "/" + config.Index
+				file, info, err = openStatic(fileSystem, name)
+			}
+			if nil != err {
+				context.notFound()
+				return
+			}
+		}
+		defer file.Close()
+
+		if info.IsDir() {
+			//目录需要以 '/' 结尾, 页面中的相对链接才能正确解析
+			//与 http.FileServer 一样使用相对地址, 避免 "//evil.example" 这样的路径变成协议相对的跳转
+			if !strings.HasSuffix(context.Request.URL.Path, "/") {
+				target := path.Base(context.Request.URL.Path) + "/"
+				if context.Request.URL.RawQuery != "" {
+					target += "?" + context.Request.URL.RawQuery
+				}
+				context.SetHeader("Location", target)
+				context.SetCode(http.StatusMovedPermanently)
+				return
+			}
+			index, indexInfo, err := openStatic(fileSystem, path.Join(name, config.Index))
+			if nil == err && !indexInfo.IsDir() {
+				defer index.Close()
+				config.serveStatic(context, fileSystem, path.Join(name, config.Index), index, indexInfo)
+				return
+			}
+			if nil == err {
+				index.Close()
+			}
+			if config.Browse {
+				fileServer.ServeHTTP(context.Writer, context.Request)
+				return
+			}
+			context.notFound()
+			return
+		}
+		config.serveStatic(context, fileSystem, name, file, info)
+	}
+}
+
+//Static 以 root 目录提供静态文件, 不列出目录
+func (this *RouterGroup) Static(relativePath, root string) {
+	this.StaticFS(relativePath, http.Dir(root))
+}
+
+//StaticFS 以 fileSystem 提供静态文件, 不列出目录
+func (this *RouterGroup) StaticFS(relativePath string, fileSystem http.FileSystem) {
+	this.StaticWithConfig(relativePath, fileSystem, defaultStaticConfig)
+}
+
+//StaticWithConfig 按 config 提供静态文件, 可用于开启目录列表, 缓存头, 预压缩和 SPA 模式
+func (this *RouterGroup) StaticWithConfig(relativePath string, fileSystem http.FileSystem, config StaticConfig) {
+	if strings.ContainsAny(relativePath, ":*") {
+		panic("dew: URL parameters can not be used when serving a static folder")
+	}
+	handler := this.createStaticHandler(relativePath, fileSystem, config)
+	urlPattern := path.Join(relativePath, "/*filepath")
+	//注册 GET 处理器, HEAD 请求会回退到 GET
+	this.GET(urlPattern, handler)
+}
+
+//StaticFile 把单个文件注册为静态路由
+func (this *RouterGroup) StaticFile(relativePath, filePath string) {
+	if strings.ContainsAny(relativePath, ":*") {
+		panic("dew: URL parameters can not be used when serving a static file")
+	}
+	fileSystem := http.Dir(filepath.Dir(filePath))
+	name := "/" + filepath.Base(filePath)
+	config := defaultStaticConfig
+	this.GET(relativePath, func(context *Context) {
+		file, info, err := openStatic(fileSystem, name)
+		if nil != err || info.IsDir() {
+			if nil == err {
+				file.Close()
+			}
+			context.notFound()
+			return
+		}
+		defer file.Close()
+		config.serveStatic(context, fileSystem, name, file, info)
+	})
+}
@@ -0,0 +1,126 @@
+package dew
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatic(t *testing.T) {
+	root := writeTemplates(t, map[string]string{
+		"index.html":   "home",
+		"app.js":       "console.log(1)",
+		"app.js.gz":    "gzipped",
+		"docs/a.txt":   "a",
+		"favicon.ico":  "icon",
+		"spa/app.html": "spa",
+	})
+	defer os.RemoveAll(root)
+
+	engine := CreateEngine()
+	engine.NoRoute(func(context *Context) {
+		context.WriteString(http.StatusNotFound, "missing %s", context.Path)
+	})
+	engine.Static("/static", root)
+	engine.StaticWithConfig("/assets", http.Dir(root), StaticConfig{
+		Browse: true, MaxAge: 365 * 24 * time.Hour, Immutable: true, Precompressed: true,
+	})
+	engine.StaticWithConfig("/app", http.Dir(filepath.Join(root, "spa")), StaticConfig{SPA: true, Index: "app.html"})
+	engine.StaticFile("/favicon.ico", filepath.Join(root, "favicon.ico"))
+
+	serve := func(method, target string, headers map[string]string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, target, nil)
+		for key, value := range headers {
+			request.Header.Set(key, value)
+		}
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := serve("GET", "/static/app.js", nil)
+	etag := recorder.Header().Get("ETag")
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "console.log(1)" || etag == "" {
+		t.Fatalf("unexpected file response %d %q %v", recorder.Code, recorder.Body.String(), recorder.Header())
+	}
+	if recorder := serve("GET", "/static/app.js", map[string]string{"If-None-Match": etag}); recorder.Code != http.StatusNotModified {
+		t.Fatalf("matching etag should give 304, got %d", recorder.Code)
+	}
+	if recorder := serve("GET", "/static/app.js", map[string]string{"Range": "bytes=0-6"}); recorder.Body.String() != "console" {
+		t.Fatalf("range should be supported, got %q", recorder.Body.String())
+	}
+	if recorder := serve("HEAD", "/static/app.js", nil); recorder.Code != http.StatusOK || recorder.Body.Len() != 0 {
+		t.Fatalf("HEAD should have no body, got %d %q", recorder.Code, recorder.Body.String())
+	}
+	if recorder := serve("GET", "/static/nothing.js", nil); recorder.Code != http.StatusNotFound || recorder.Body.String() != "missing /static/nothing.js" {
+		t.Fatalf("missing file should use NoRoute, got %d %q", recorder.Code, recorder.Body.String())
+	}
+	if recorder := serve("GET", "/static/", nil); recorder.Body.String() != "home" {
+		t.Fatalf("directory should serve index.html, got %q", recorder.Body.String())
+	}
+	if recorder := serve("GET", "/static/docs?x=1", nil); recorder.Code != http.StatusMovedPermanently || recorder.Header().Get("Location") != "docs/?x=1" {
+		t.Fatalf("directory should redirect to a trailing slash, got %d %v", recorder.Code, recorder.Header())
+	}
+	if recorder := serve("GET", "/static/docs/", nil); recorder.Code != http.StatusNotFound {
+		t.Fatalf("listing should be disabled by default, got %d", recorder.Code)
+	}
+
+	if recorder := serve("GET", "/assets/docs/", nil); !strings.Contains(recorder.Body.String(), "a.txt") {
+		t.Fatalf("listing should be enabled, got %q", recorder.Body.String())
+	}
+	recorder = serve("GET", "/assets/app.js", map[string]string{"Accept-Encoding": "gzip"})
+	if recorder.Header().Get("Content-Encoding") != "gzip" || recorder.Body.String() != "gzipped" ||
+		!strings.Contains(recorder.Header().Get("Content-Type"), "javascript") ||
+		recorder.Header().Get("Cache-Control") != "public, max-age=31536000, immutable" {
+		t.Fatalf("precompressed file should be served, got %v %q", recorder.Header(), recorder.Body.String())
+	}
+	if recorder := serve("GET", "/assets/app.js", nil); recorder.Body.String() != "console.log(1)" {
+		t.Fatalf("clients without gzip should get the original, got %q", recorder.Body.String())
+	}
+
+	if recorder := serve("GET", "/app/orders/1", nil); recorder.Body.String() != "spa" {
+		t.Fatalf("SPA should fall back to the index, got %d %q", recorder.Code, recorder.Body.String())
+	}
+	if recorder := serve("GET", "/app/missing.js", nil); recorder.Code != http.StatusNotFound {
+		t.Fatalf("missing assets should not fall back, got %d", recorder.Code)
+	}
+	if recorder := serve("GET", "/favicon.ico", nil); recorder.Body.String() != "icon" {
+		t.Fatalf("static file should be served, got %q", recorder.Body.String())
+	}
+}
+
+func TestStaticRoot(t *testing.T) {
+	root := writeTemplates(t, map[string]string{"evil.example/index.html": "local"})
+	defer os.RemoveAll(root)
+
+	var order []string
+	engine := CreateEngine()
+	engine.NoRoute(func(context *Context) {
+		order = append(order, "before")
+		context.Next()
+		order = append(order, "after")
+	}, func(context *Context) {
+		order = append(order, "404")
+		context.WriteString(http.StatusNotFound, "missing")
+	})
+	engine.Static("/", root)
+
+	//挂载在根路径时, "//evil.example" 不能变成跳转到其他站点的协议相对地址
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "/", nil)
+	request.URL.Path = "//evil.example"
+	engine.ServeHTTP(recorder, request)
+	if location := recorder.Header().Get("Location"); recorder.Code != http.StatusMovedPermanently || location != "evil.example/" {
+		t.Fatalf("directory redirect should be relative, got %d %q", recorder.Code, location)
+	}
+
+	recorder = httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("GET", "/missing.txt", nil))
+	if recorder.Code != http.StatusNotFound || strings.Join(order, ",") != "before,404,after" {
+		t.Fatalf("NoRoute handlers should run as a chain, got %d %v", recorder.Code, order)
+	}
+}
@@ -0,0 +1,142 @@
+package dew
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//SSE 一条 server-sent event, Data 为字符串时原样按行输出, 否则编码为 JSON
+type SSE struct {
+	Event string
+	ID    string
+	//Retry 建议客户端断线后的重连间隔, 0 表示不设置
+	Retry time.Duration
+	Data  interface{}
+}
+
+//sseFieldReplacer 事件名和 ID 中不能出现换行
+var sseFieldReplacer = strings.NewReplacer("\n", "", "\r", "")
+
+//WriteTo 按 text/event-stream 格式写出事件
+func (this SSE) WriteTo(writer io.Writer) (int64, error) {
+	var builder strings.Builder
+	if this.ID != "" {
+		builder.WriteString("id: " + sseFieldReplacer.Replace(this.ID) + "\n")
+	}
+	if this.Event != "" {
+		builder.WriteString("event: " + sseFieldReplacer.Replace(this.Event) + "\n")
+	}
+	if this.Retry > 0 {
+		builder.WriteString("retry: " + strconv.FormatInt(int64(this.Retry/time.Millisecond), 10) + "\n")
+	}
+	var data string
+	switch value := this.Data.(type) {
+	case string:
+		data = value
+	case []byte:
+		data = string(value)
+	default:
+		encoded, err := json.Marshal(value)
+		if nil != err {
+			return 0, err
+		}
+		data = string(encoded)
+	}
+	data = strings.Replace(data, "\r\n", "\n", -1)
+	for _, line := range strings.Split(data, "\n") {
+		builder.WriteString("data: " + line + "\n")
+	}
+	builder.WriteString("\n")
+	written, err := io.WriteString(writer, builder.String())
+	return int64(written), err
+}
+
+//setStreamHeaders 事件流不能被缓存, 也不能被 nginx 等代理缓冲
+func (this *Context) setStreamHeaders() {
+	header := this.Writer.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "text/event-stream")
+	}
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+}
+
+//Stream 反复调用 step 并在每次之后刷新, step 返回 false 时结束并返回 false, 客户端断开时返回 true
+func (this *Context) Stream(step func(writer io.Writer) bool) bool {
+	done := this.Request.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		default:
+			keepOpen := step(this.Writer)
+			this.Writer.Flush()
+			if !keepOpen {
+				return false
+			}
+		}
+	}
+}
+
+//SSEvent 写出一条事件并立即刷新
+func (this *Context) SSEvent(name string, data interface{}) error {
+	return this.WriteSSE(SSE{Event: name, Data: data})
+}
+
+//WriteSSE 写出一条完整的事件并立即刷新
+func (this *Context) WriteSSE(event SSE) error {
+	this.setStreamHeaders()
+	if _, err := event.WriteTo(this.Writer); nil != err {
+		return err
+	}
+	this.Writer.Flush()
+	return nil
+}
+
+//SSEComment 写出一行注释, 客户端会忽略它, 可用于保持连接
+func (this *Context) SSEComment(comment string) error {
+	this.setStreamHeaders()
+	if _, err := fmt.Fprintf(this.Writer, ": %s\n\n", sseFieldReplacer.Replace(comment)); nil != err {
+		return err
+	}
+	this.Writer.Flush()
+	return nil
+}
+
+//StreamSSE 持续发送 events 中的事件, 每隔 keepAlive 没有事件时发送一行注释防止代理断开空闲连接
+//events 关闭时返回 false, 客户端断开时返回 true
+func (this *Context) StreamSSE(events <-chan SSE, keepAlive time.Duration) bool {
+	this.setStreamHeaders()
+	this.Writer.WriteHeaderNow()
+	this.Writer.Flush()
+
+	var ticks <-chan time.Time
+	if keepAlive > 0 {
+		ticker := time.NewTicker(keepAlive)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+	done := this.Request.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			if nil != this.WriteSSE(event) {
+				return true
+			}
+		case <-ticks:
+			if nil != this.SSEComment("keep-alive") {
+				return true
+			}
+		}
+	}
+}
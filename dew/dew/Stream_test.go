@@ -0,0 +1,99 @@
+package dew
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSEWriteTo(t *testing.T) {
+	var buffer bytes.Buffer
+	SSE{ID: "7", Event: "order\nstatus", Retry: 3 * time.Second, Data: "paid\nshipped"}.WriteTo(&buffer)
+	SSE{Data: H{"id": 1}}.WriteTo(&buffer)
+	expected := "id: 7\nevent: orderstatus\nretry: 3000\ndata: paid\ndata: shipped\n\n" +
+		"data: {\"id\":1}\n\n"
+	if buffer.String() != expected {
+		t.Fatalf("unexpected events %q", buffer.String())
+	}
+}
+
+func TestStream(t *testing.T) {
+	engine := CreateEngine()
+	engine.GET("/count", func(context *Context) {
+		count := 0
+		context.Stream(func(writer io.Writer) bool {
+			count++
+			fmt.Fprintf(writer, "%d;", count)
+			return count < 3
+		})
+	})
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("GET", "/count", nil))
+	if recorder.Body.String() != "1;2;3;" || !recorder.Flushed {
+		t.Fatalf("unexpected stream %q flushed=%v", recorder.Body.String(), recorder.Flushed)
+	}
+}
+
+func TestStreamSSE(t *testing.T) {
+	events := make(chan SSE)
+	disconnected := make(chan bool, 1)
+	engine := CreateEngine()
+	engine.GET("/events", func(context *Context) {
+		disconnected <- context.StreamSSE(events, 20*time.Millisecond)
+	})
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/events")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if response.Header.Get("Content-Type") != "text/event-stream" || response.Header.Get("Cache-Control") != "no-cache" {
+		t.Fatalf("unexpected headers %v", response.Header)
+	}
+	reader := bufio.NewReader(response.Body)
+	readBlock := func() string {
+		var lines []string
+		for {
+			line, err := reader.ReadString('\n')
+			if nil != err {
+				t.Fatal(err)
+			}
+			if line == "\n" {
+				return strings.Join(lines, "")
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	if block := readBlock(); block != ": keep-alive\n" {
+		t.Fatalf("expected a keep-alive comment, got %q", block)
+	}
+	events <- SSE{Event: "status", Data: "paid"}
+	for {
+		block := readBlock()
+		if block == ": keep-alive\n" {
+			continue
+		}
+		if block != "event: status\ndata: paid\n" {
+			t.Fatalf("unexpected event %q", block)
+		}
+		break
+	}
+
+	response.Body.Close()
+	select {
+	case gone := <-disconnected:
+		if !gone {
+			t.Fatal("disconnect should be reported")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler did not notice the disconnect")
+	}
+}
@@ -1,78 +1,208 @@
 package dew
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
+//Param 路由参数
+type Param struct {
+	Key   string
+	Value string
+}
+
+//Params 按出现顺序保存的路由参数, 查找时复用同一个切片
+type Params []Param
+
+//Get 返回参数值和是否存在
+func (this Params) Get(key string) (string, bool) {
+	for _, param := range this {
+		if param.Key == key {
+			return param.Value, true
+		}
+	}
+	return "", false
+}
+
+//ByName 返回参数值, 不存在时返回空串
+func (this Params) ByName(key string) string {
+	value, _ := this.Get(key)
+	return value
+}
+
+//压缩前缀树节点, 子节点优先级: 静态 > :param > *catchall
 type node struct {
-	pattern  string  //待匹配路由
-	part     string  //路由中的一部分
-	children []*node //子节点
-	isWild   bool    //是否精确匹配
+	pattern       string  //完整路由, 非空表示可匹配
+	path          string  //静态节点为压缩后的前缀, 通配节点为 :name 或 *name
+	indices       string  //静态子节点 path 的首字节, 与 children 一一对应
+	children      []*node //静态子节点
+	paramChild    *node   //:param 子节点
+	catchAllChild *node   //*catchall 子节点
+	//路由自身的处理器, 以及加上分组中间件后预先计算好的完整处理链
+	handlers []HandlerFunction
+	chain    []HandlerFunction
 }
 
-func (this *node) travel(list []*node) {
+//travel 收集所有可匹配的节点
+func (this *node) travel(list *[]*node) {
 	if this.pattern != "" {
-		list = append(list, this)
+		*list = append(*list, this)
 	}
 
 	for _, child := range this.children {
 		child.travel(list)
 	}
+	if nil != this.paramChild {
+		this.paramChild.travel(list)
+	}
+	if nil != this.catchAllChild {
+		this.catchAllChild.travel(list)
+	}
 }
 
-//第一个匹配成功的节点,用于插入
-func (this *node) matchChild(part string) *node {
-	for _, child := range this.children {
-		if child.part == part || child.isWild {
-			return child
-		}
+//isWildStart 判断 path[i] 是否为通配段的起点, 通配符必须紧跟 '/'
+func isWildStart(path, pattern string, i int) bool {
+	if path[i] != ':' && path[i] != '*' {
+		return false
 	}
-	return nil
+	if i > 0 {
+		return path[i-1] == '/'
+	}
+	offset := len(pattern) - len(path)
+	return offset > 0 && pattern[offset-1] == '/'
 }
 
-//所有匹配成功的节点,用于查找
-func (this *node) matchChildren(part string) []*node {
-	nodes := make([]*node, 0)
-	for _, child := range this.children {
-		if child.part == part || child.isWild {
-			nodes = append(nodes, child)
-		}
+func longestCommonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
 	}
-	return nodes
+	return i
 }
 
-func (this *node) insert(pattern string, parts []string, height int) {
-	if len(parts) == height {
+//split 在第 l 个字节处把静态节点拆成父子两个节点
+func (this *node) split(l int) {
+	child := *this
+	child.path = this.path[l:]
+	*this = node{
+		path:     this.path[:l],
+		indices:  child.path[:1],
+		children: []*node{&child},
+	}
+}
+
+//insert 把 path(pattern 尚未消费的部分) 插入到当前节点之下, 返回路由对应的节点
+func (this *node) insert(path, pattern string) *node {
+	if path == "" {
+		if this.pattern != "" {
+			panic(fmt.Sprintf("dew: route '%s' conflicts with existing route '%s'", pattern, this.pattern))
+		}
 		this.pattern = pattern
-		return
+		return this
+	}
+
+	if isWildStart(path, pattern, 0) {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		wild := path[:end]
+		if strings.ContainsAny(wild[1:], ":*") {
+			panic(fmt.Sprintf("dew: only one wildcard per path segment is allowed in route '%s'", pattern))
+		}
+
+		if wild[0] == '*' {
+			if end != len(path) {
+				panic(fmt.Sprintf("dew: catch-all '%s' must be at the end of route '%s'", wild, pattern))
+			}
+			if nil == this.catchAllChild {
+				this.catchAllChild = &node{path: wild}
+			} else if this.catchAllChild.path != wild {
+				panic(fmt.Sprintf("dew: catch-all '%s' in route '%s' conflicts with existing '%s'", wild, pattern, this.catchAllChild.path))
+			}
+			return this.catchAllChild.insert("", pattern)
+		}
+
+		if len(wild) == 1 {
+			panic(fmt.Sprintf("dew: wildcards must be named in route '%s'", pattern))
+		}
+		if nil == this.paramChild {
+			this.paramChild = &node{path: wild}
+		} else if this.paramChild.path != wild {
+			panic(fmt.Sprintf("dew: wildcard '%s' in route '%s' conflicts with existing '%s'", wild, pattern, this.paramChild.path))
+		}
+		return this.paramChild.insert(path[end:], pattern)
 	}
 
-	part := parts[height]
-	child := this.matchChild(part)
-	if nil == child {
-		child = &node{
-			part:   part,
-			isWild: part[0] == ':' || part[0] == '*',
+	//静态部分截止到下一个通配段
+	end := 1
+	for end < len(path) && !isWildStart(path, pattern, end) {
+		end++
+	}
+	static := path[:end]
+
+	for i := 0; i < len(this.indices); i++ {
+		if this.indices[i] == static[0] {
+			child := this.children[i]
+			l := longestCommonPrefix(child.path, static)
+			if l < len(child.path) {
+				child.split(l)
+			}
+			return child.insert(path[l:], pattern)
 		}
-		this.children = append(this.children, child)
 	}
-	child.insert(pattern, parts, height+1)
+
+	child := &node{path: static}
+	this.indices += static[:1]
+	this.children = append(this.children, child)
+	return child.insert(path[end:], pattern)
 }
 
-func (this *node) search(parts []string, height int) *node {
-	if len(parts) == height || strings.HasPrefix(this.part, "*") {
-		if this.pattern == "" {
-			return nil
+//search 查找 path(当前节点之后的剩余部分), 参数写入 params, 失败时回溯, 不分配内存
+func (this *node) search(path string, params *Params) *node {
+	if path == "" {
+		if this.pattern != "" {
+			return this
+		}
+	} else {
+		c := path[0]
+		for i := 0; i < len(this.indices); i++ {
+			if this.indices[i] == c {
+				child := this.children[i]
+				if strings.HasPrefix(path, child.path) {
+					if result := child.search(path[len(child.path):], params); nil != result {
+						return result
+					}
+				}
+				break
+			}
+		}
+
+		if child := this.paramChild; nil != child {
+			end := strings.IndexByte(path, '/')
+			if end < 0 {
+				end = len(path)
+			}
+			if end > 0 {
+				*params = append(*params, Param{Key: child.path[1:], Value: path[:end]})
+				if result := child.search(path[end:], params); nil != result {
+					return result
+				}
+				*params = (*params)[:len(*params)-1]
+			}
 		}
-		return this
 	}
 
-	part := parts[height]
-	children := this.matchChildren(part)
-	for _, child := range children {
-		result := child.search(parts, height+1)
-		if nil != result {
-			return result
+	if child := this.catchAllChild; nil != child {
+		if len(child.path) > 1 {
+			*params = append(*params, Param{Key: child.path[1:], Value: path})
 		}
+		return child
+	}
+
+	//容忍结尾多出的 '/'
+	if path == "/" && this.pattern != "" {
+		return this
 	}
 	return nil
 }
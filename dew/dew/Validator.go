@@ -0,0 +1,257 @@
+package dew
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+type (
+	//StructValidator 绑定完成后对结构体进行校验, 替换 Validator 即可接入其他校验库
+	StructValidator interface {
+		ValidateStruct(object interface{}) error
+	}
+
+	//ValidationRule 校验规则, param 为标签中 '=' 之后的部分
+	ValidationRule func(field reflect.Value, param string) bool
+
+	//FieldError 单个字段的校验失败信息, Field 优先使用 json 或 form 标签中的名称
+	//Value 可能是密码等敏感数据, 不会随 Context.Fail 返回给客户端
+	FieldError struct {
+		Field string      `json:"field"`
+		Tag   string      `json:"tag"`
+		Param string      `json:"param,omitempty"`
+		Value interface{} `json:"-"`
+	}
+
+	//ValidationErrors 校验失败的字段列表, Context.Fail 会原样渲染
+	ValidationErrors []FieldError
+
+	//defaultValidator 读取 `binding:"required,min=1"` 形式的标签
+	defaultValidator struct {
+		mutex sync.RWMutex
+		rules map[string]ValidationRule
+	}
+)
+
+//Validator 绑定时使用的校验器, 设为 nil 可关闭校验
+var Validator StructValidator = &defaultValidator{
+	rules: map[string]ValidationRule{
+		"required": validateRequired,
+		"min":      validateMin,
+		"max":      validateMax,
+		"len":      validateLen,
+		"email":    validateEmail,
+		"oneof":    validateOneOf,
+	},
+}
+
+var emailRegexp = regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`)
+
+func (this FieldError) Error() string {
+	if this.Param != "" {
+		return fmt.Sprintf("field '%s' failed on '%s=%s'", this.Field, this.Tag, this.Param)
+	}
+	return fmt.Sprintf("field '%s' failed on '%s'", this.Field, this.Tag)
+}
+
+func (this ValidationErrors) Error() string {
+	messages := make([]string, len(this))
+	for i, item := range this {
+		messages[i] = item.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+//RegisterValidation 为默认校验器注册规则, 同名规则会被覆盖
+func RegisterValidation(name string, rule ValidationRule) {
+	validator, ok := Validator.(*defaultValidator)
+	if !ok {
+		panic("dew: RegisterValidation requires the default validator")
+	}
+	validator.mutex.Lock()
+	defer validator.mutex.Unlock()
+	validator.rules[name] = rule
+}
+
+func validate(object interface{}) error {
+	if nil == Validator {
+		return nil
+	}
+	return Validator.ValidateStruct(object)
+}
+
+func (this *defaultValidator) ValidateStruct(object interface{}) error {
+	value := reflect.ValueOf(object)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	errs := this.validateStruct(value, "", nil)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (this *defaultValidator) validateStruct(value reflect.Value, namespace string, errs ValidationErrors) ValidationErrors {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		name := namespace + fieldName(field)
+		fieldValue := value.Field(i)
+
+		if tag := field.Tag.Get("binding"); tag != "" && tag != "-" {
+			errs = this.validateField(fieldValue, name, tag, errs)
+		}
+
+		for fieldValue.Kind() == reflect.Ptr && !fieldValue.IsNil() {
+			fieldValue = fieldValue.Elem()
+		}
+		if fieldValue.Kind() == reflect.Struct && fieldValue.Type() != timeType {
+			//与 JSON 一样, 没有命名的嵌入结构体中的字段视为外层字段
+			if field.Anonymous && fieldName(field) == field.Name {
+				errs = this.validateStruct(fieldValue, namespace, errs)
+			} else {
+				errs = this.validateStruct(fieldValue, name+".", errs)
+			}
+		}
+	}
+	return errs
+}
+
+//fieldName 客户端看到的字段名, 依次取 json 和 form 标签, 都没有时使用字段名
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name := field.Tag.Get(tag)
+		if index := strings.IndexByte(name, ','); index >= 0 {
+			name = name[:index]
+		}
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+func (this *defaultValidator) validateField(value reflect.Value, name, tag string, errs ValidationErrors) ValidationErrors {
+	rules := strings.Split(tag, ",")
+	for _, rule := range rules {
+		if rule == "omitempty" && value.IsZero() {
+			return errs
+		}
+	}
+
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if rule == "" || rule == "omitempty" {
+			continue
+		}
+		ruleName, param := rule, ""
+		if index := strings.IndexByte(rule, '='); index >= 0 {
+			ruleName, param = rule[:index], rule[index+1:]
+		}
+
+		check, ok := this.rules[ruleName]
+		if !ok {
+			panic(fmt.Sprintf("dew: undefined validation rule '%s' on field '%s'", ruleName, name))
+		}
+
+		field := value
+		if ruleName != "required" {
+			for field.Kind() == reflect.Ptr {
+				if field.IsNil() {
+					break
+				}
+				field = field.Elem()
+			}
+			//空指针只由 required 负责
+			if field.Kind() == reflect.Ptr {
+				continue
+			}
+		}
+		if !check(field, param) {
+			fieldError := FieldError{Field: name, Tag: ruleName, Param: param}
+			if field.CanInterface() {
+				fieldError.Value = field.Interface()
+			}
+			errs = append(errs, fieldError)
+		}
+	}
+	return errs
+}
+
+func validateRequired(field reflect.Value, param string) bool {
+	return !field.IsZero()
+}
+
+//sizeOf 字符串取字符数, 容器取长度, 数字取数值
+func sizeOf(field reflect.Value) (float64, bool) {
+	switch field.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(field.String())), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(field.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(field.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(field.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return field.Float(), true
+	}
+	return 0, false
+}
+
+func compareSize(field reflect.Value, param string, compare func(size, limit float64) bool) bool {
+	limit, err := strconv.ParseFloat(param, 64)
+	if nil != err {
+		panic(fmt.Sprintf("dew: invalid validation param '%s'", param))
+	}
+	size, ok := sizeOf(field)
+	return ok && compare(size, limit)
+}
+
+func validateMin(field reflect.Value, param string) bool {
+	return compareSize(field, param, func(size, limit float64) bool { return size >= limit })
+}
+
+func validateMax(field reflect.Value, param string) bool {
+	return compareSize(field, param, func(size, limit float64) bool { return size <= limit })
+}
+
+func validateLen(field reflect.Value, param string) bool {
+	return compareSize(field, param, func(size, limit float64) bool { return size == limit })
+}
+
+func validateEmail(field reflect.Value, param string) bool {
+	return field.Kind() == reflect.String && emailRegexp.MatchString(field.String())
+}
+
+//validateOneOf 参数以空格分隔, 例如 `binding:"oneof=red green"`
+func validateOneOf(field reflect.Value, param string) bool {
+	if !field.CanInterface() {
+		return false
+	}
+	value := fmt.Sprint(field.Interface())
+	for _, item := range strings.Fields(param) {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,456 @@
+package dew
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+//消息类型, 与 RFC 6455 的 opcode 相同
+const (
+	WSTextMessage   = 1
+	WSBinaryMessage = 2
+	WSCloseMessage  = 8
+	WSPingMessage   = 9
+	WSPongMessage   = 10
+
+	wsContinuation = 0
+)
+
+//关闭码, 见 RFC 6455 7.4.1
+const (
+	WSCloseNormalClosure           = 1000
+	WSCloseGoingAway               = 1001
+	WSCloseProtocolError           = 1002
+	WSCloseUnsupportedData         = 1003
+	WSCloseNoStatusReceived        = 1005
+	WSCloseAbnormalClosure         = 1006
+	WSCloseInvalidFramePayloadData = 1007
+	WSClosePolicyViolation         = 1008
+	WSCloseMessageTooBig           = 1009
+	WSCloseInternalServerErr       = 1011
+)
+
+//wsGUID 计算 Sec-WebSocket-Accept 使用的固定 GUID
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+//ErrWSClosed 已发送关闭帧后不能再写入消息
+var ErrWSClosed = errors.New("dew: websocket connection is closed")
+
+type (
+	//WSHandler 处理一个已完成握手的连接, 返回后连接会被关闭
+	WSHandler func(context *Context, conn *WSConn)
+
+	//WSUpgrader 握手配置, 零值即可使用
+	WSUpgrader struct {
+		//CheckOrigin 返回 false 时拒绝握手, 默认要求 Origin 与 Host 相同, 没有 Origin 的非浏览器客户端总是允许
+		CheckOrigin func(request *http.Request) bool
+		//Subprotocols 服务端支持的子协议, 按客户端给出的顺序选择第一个支持的
+		Subprotocols []string
+		//ReadLimit 单条消息的最大字节数, 默认 1MB, 超出时以 1009 关闭连接
+		ReadLimit int64
+		//WriteFragmentSize 大于 0 时, 超过该大小的消息会被拆分成多个帧发送
+		WriteFragmentSize int
+	}
+
+	//WSCloseError 对端发送了关闭帧, ReadMessage 返回该错误
+	WSCloseError struct {
+		Code int
+		Text string
+	}
+
+	//WSConn 面向消息的 WebSocket 连接, 同一时间只能有一个 goroutine 读, 写可以并发
+	WSConn struct {
+		conn              net.Conn
+		reader            *bufio.Reader
+		writer            *bufio.Writer
+		writeMutex        sync.Mutex
+		subprotocol       string
+		readLimit         int64
+		writeFragmentSize int
+		closeSent         bool
+		pongHandler       func(data []byte)
+	}
+)
+
+func (this *WSCloseError) Error() string {
+	return fmt.Sprintf("dew: websocket closed with code %d %s", this.Code, this.Text)
+}
+
+//headerContains 判断以逗号分隔的头中是否包含 token, 不区分大小写
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header[http.CanonicalHeaderKey(name)] {
+		for _, item := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(item), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sameOrigin(request *http.Request) bool {
+	origin := request.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	parsed, err := url.Parse(origin)
+	return nil == err && strings.EqualFold(parsed.Host, request.Host)
+}
+
+func wsAccept(key string) string {
+	hash := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+//Upgrade 校验握手请求并接管连接, 失败时已经写出了错误响应
+func (this *WSUpgrader) Upgrade(context *Context) (*WSConn, error) {
+	request := context.Request
+	if request.Method != http.MethodGet ||
+		!headerContains(request.Header, "Connection", "upgrade") ||
+		!headerContains(request.Header, "Upgrade", "websocket") {
+		context.Fail(http.StatusBadRequest, "not a websocket handshake")
+		return nil, errors.New("dew: not a websocket handshake")
+	}
+	if request.Header.Get("Sec-WebSocket-Version") != "13" {
+		context.SetHeader("Sec-WebSocket-Version", "13")
+		context.Fail(http.StatusUpgradeRequired, "unsupported websocket version")
+		return nil, errors.New("dew: unsupported websocket version")
+	}
+	key := request.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); nil != err || len(decoded) != 16 {
+		context.Fail(http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+		return nil, errors.New("dew: invalid Sec-WebSocket-Key")
+	}
+	checkOrigin := this.CheckOrigin
+	if nil == checkOrigin {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(request) {
+		context.Fail(http.StatusForbidden, "origin not allowed")
+		return nil, errors.New("dew: websocket origin not allowed")
+	}
+
+	subprotocol := ""
+	for _, offered := range strings.Split(request.Header.Get("Sec-WebSocket-Protocol"), ",") {
+		offered = strings.TrimSpace(offered)
+		for _, supported := range this.Subprotocols {
+			if subprotocol == "" && offered == supported {
+				subprotocol = supported
+			}
+		}
+	}
+
+	//先接管连接再写 101, HTTP/2 等不支持接管的连接上仍能返回普通的错误响应
+	conn, buffered, err := context.Writer.Hijack()
+	if nil != err {
+		context.Fail(http.StatusInternalServerError, "websocket upgrade failed")
+		return nil, err
+	}
+	response := "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + wsAccept(key) + "\r\n"
+	if subprotocol != "" {
+		response += "Sec-WebSocket-Protocol: " + subprotocol + "\r\n"
+	}
+	//握手阶段设置的超时不应影响之后的长连接
+	conn.SetDeadline(time.Time{})
+	buffered.Writer.WriteString(response + "\r\n")
+	if err := buffered.Writer.Flush(); nil != err {
+		conn.Close()
+		return nil, err
+	}
+
+	readLimit := this.ReadLimit
+	if readLimit <= 0 {
+		readLimit = 1 << 20
+	}
+	return &WSConn{
+		conn:              conn,
+		reader:            buffered.Reader,
+		writer:            buffered.Writer,
+		subprotocol:       subprotocol,
+		readLimit:         readLimit,
+		writeFragmentSize: this.WriteFragmentSize,
+	}, nil
+}
+
+//Handler 把 WSHandler 包装为路由处理器
+func (this *WSUpgrader) Handler(handler WSHandler) HandlerFunction {
+	return func(context *Context) {
+		conn, err := this.Upgrade(context)
+		if nil != err {
+			context.Error(err)
+			return
+		}
+		defer conn.Close()
+		handler(context, conn)
+	}
+}
+
+//WS 以默认配置注册一个 WebSocket 路由
+func (this *RouterGroup) WS(pattern string, handler WSHandler) {
+	upgrader := &WSUpgrader{}
+	this.GET(pattern, upgrader.Handler(handler))
+}
+
+func (this *WSConn) Subprotocol() string {
+	return this.subprotocol
+}
+
+func (this *WSConn) RemoteAddr() net.Addr {
+	return this.conn.RemoteAddr()
+}
+
+func (this *WSConn) SetReadDeadline(deadline time.Time) error {
+	return this.conn.SetReadDeadline(deadline)
+}
+
+func (this *WSConn) SetWriteDeadline(deadline time.Time) error {
+	return this.conn.SetWriteDeadline(deadline)
+}
+
+//SetPongHandler 收到 pong 时调用, 可用于配合 Ping 检测连接是否存活
+func (this *WSConn) SetPongHandler(handler func(data []byte)) {
+	this.pongHandler = handler
+}
+
+//writeFrame 服务端发送的帧不加掩码
+func (this *WSConn) writeFrame(fin bool, opcode int, payload []byte) error {
+	var header [10]byte
+	header[0] = byte(opcode)
+	if fin {
+		header[0] |= 0x80
+	}
+	size := 2
+	switch length := len(payload); {
+	case length <= 125:
+		header[1] = byte(length)
+	case length <= 0xffff:
+		header[1] = 126
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+		size = 4
+	default:
+		header[1] = 127
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+		size = 10
+	}
+	this.writer.Write(header[:size])
+	this.writer.Write(payload)
+	return this.writer.Flush()
+}
+
+//WriteMessage 发送一条文本或二进制消息
+func (this *WSConn) WriteMessage(messageType int, data []byte) error {
+	if messageType != WSTextMessage && messageType != WSBinaryMessage {
+		return fmt.Errorf("dew: invalid websocket message type %d", messageType)
+	}
+	this.writeMutex.Lock()
+	defer this.writeMutex.Unlock()
+	if this.closeSent {
+		return ErrWSClosed
+	}
+	opcode := messageType
+	for this.writeFragmentSize > 0 && len(data) > this.writeFragmentSize {
+		if err := this.writeFrame(false, opcode, data[:this.writeFragmentSize]); nil != err {
+			return err
+		}
+		data = data[this.writeFragmentSize:]
+		opcode = wsContinuation
+	}
+	return this.writeFrame(true, opcode, data)
+}
+
+func (this *WSConn) WriteText(text string) error {
+	return this.WriteMessage(WSTextMessage, []byte(text))
+}
+
+//WriteJSON 以文本消息发送 value 的 JSON 编码
+func (this *WSConn) WriteJSON(value interface{}) error {
+	data, err := json.Marshal(value)
+	if nil != err {
+		return err
+	}
+	return this.WriteMessage(WSTextMessage, data)
+}
+
+//writeControl 控制帧可以插在分片消息之间发送, payload 不能超过 125 字节
+func (this *WSConn) writeControl(opcode int, payload []byte) error {
+	if len(payload) > 125 {
+		return errors.New("dew: websocket control frame payload too long")
+	}
+	this.writeMutex.Lock()
+	defer this.writeMutex.Unlock()
+	if this.closeSent {
+		return ErrWSClosed
+	}
+	if opcode == WSCloseMessage {
+		this.closeSent = true
+	}
+	return this.writeFrame(true, opcode, payload)
+}
+
+func (this *WSConn) Ping(data []byte) error {
+	return this.writeControl(WSPingMessage, data)
+}
+
+//WriteClose 发送关闭帧, 之后应继续读取直到收到对端的关闭帧
+func (this *WSConn) WriteClose(code int, text string) error {
+	payload := make([]byte, 2, 2+len(text))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	return this.writeControl(WSCloseMessage, append(payload, text...))
+}
+
+//Close 尚未发送关闭帧时以 1000 关闭, 然后断开底层连接
+func (this *WSConn) Close() error {
+	this.WriteClose(WSCloseNormalClosure, "")
+	return this.conn.Close()
+}
+
+//fail 协议错误时以 code 关闭并返回错误
+func (this *WSConn) fail(code int, text string) error {
+	this.WriteClose(code, text)
+	return fmt.Errorf("dew: websocket %s", text)
+}
+
+//readFrame 读取一帧并去掉掩码, 客户端发送的帧必须带掩码
+func (this *WSConn) readFrame(remaining int64) (bool, int, []byte, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(this.reader, header[:2]); nil != err {
+		return false, 0, nil, err
+	}
+	fin := header[0]&0x80 != 0
+	opcode := int(header[0] & 0x0f)
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, this.fail(WSCloseProtocolError, "reserved bits are set")
+	}
+	if header[1]&0x80 == 0 {
+		return false, 0, nil, this.fail(WSCloseProtocolError, "client frame is not masked")
+	}
+	length := int64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		if _, err := io.ReadFull(this.reader, header[:2]); nil != err {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(header[:2]))
+	case 127:
+		if _, err := io.ReadFull(this.reader, header[:8]); nil != err {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(header[:8]))
+		if length < 0 {
+			return false, 0, nil, this.fail(WSCloseProtocolError, "invalid payload length")
+		}
+	}
+	if opcode >= WSCloseMessage {
+		if !fin || length > 125 {
+			return false, 0, nil, this.fail(WSCloseProtocolError, "invalid control frame")
+		}
+	} else if length > remaining {
+		return false, 0, nil, this.fail(WSCloseMessageTooBig, "message too big")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(this.reader, mask[:]); nil != err {
+		return false, 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(this.reader, payload); nil != err {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i&3]
+	}
+	return fin, opcode, payload, nil
+}
+
+//validCloseCode 对端可以发送的关闭码
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1011, code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+//ReadMessage 读取一条完整的消息, 自动合并分片并回复 ping, 收到关闭帧时回复后返回 *WSCloseError
+func (this *WSConn) ReadMessage() (int, []byte, error) {
+	messageType := 0
+	var message []byte
+	for {
+		fin, opcode, payload, err := this.readFrame(this.readLimit - int64(len(message)))
+		if nil != err {
+			return 0, nil, err
+		}
+		switch opcode {
+		case WSPingMessage:
+			if err := this.writeControl(WSPongMessage, payload); nil != err && err != ErrWSClosed {
+				return 0, nil, err
+			}
+			continue
+		case WSPongMessage:
+			if nil != this.pongHandler {
+				this.pongHandler(payload)
+			}
+			continue
+		case WSCloseMessage:
+			closeError := &WSCloseError{Code: WSCloseNoStatusReceived}
+			if len(payload) >= 2 {
+				closeError.Code = int(binary.BigEndian.Uint16(payload))
+				closeError.Text = string(payload[2:])
+			}
+			switch {
+			case len(payload) == 1 || len(payload) >= 2 && !validCloseCode(closeError.Code):
+				return 0, nil, this.fail(WSCloseProtocolError, "invalid close code")
+			case !utf8.ValidString(closeError.Text):
+				return 0, nil, this.fail(WSCloseInvalidFramePayloadData, "invalid close reason")
+			case closeError.Code == WSCloseNoStatusReceived:
+				this.WriteClose(WSCloseNormalClosure, "")
+			default:
+				this.WriteClose(closeError.Code, "")
+			}
+			return 0, nil, closeError
+		case WSTextMessage, WSBinaryMessage:
+			if messageType != 0 {
+				return 0, nil, this.fail(WSCloseProtocolError, "expected continuation frame")
+			}
+			messageType = opcode
+			message = payload
+		case wsContinuation:
+			if messageType == 0 {
+				return 0, nil, this.fail(WSCloseProtocolError, "unexpected continuation frame")
+			}
+			message = append(message, payload...)
+		default:
+			return 0, nil, this.fail(WSCloseProtocolError, "unknown opcode")
+		}
+
+		if fin {
+			if messageType == WSTextMessage && !utf8.Valid(message) {
+				return 0, nil, this.fail(WSCloseInvalidFramePayloadData, "invalid utf-8 text")
+			}
+			return messageType, message, nil
+		}
+	}
+}
+
+//ReadJSON 读取一条消息并以 JSON 解码到 value
+func (this *WSConn) ReadJSON(value interface{}) error {
+	_, data, err := this.ReadMessage()
+	if nil != err {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
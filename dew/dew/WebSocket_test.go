@@ -0,0 +1,216 @@
+package dew
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+//wsTestClient 测试用的最小客户端, 发送的帧都带掩码
+type wsTestClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialWS(t *testing.T, server *httptest.Server, path string, headers map[string]string) (*wsTestClient, *http.Response) {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	request := "GET " + path + " HTTP/1.1\r\nHost: " + strings.TrimPrefix(server.URL, "http://") + "\r\n" +
+		"Connection: Upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Version: 13\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"
+	for key, value := range headers {
+		request += key + ": " + value + "\r\n"
+	}
+	io.WriteString(conn, request+"\r\n")
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, nil)
+	if nil != err {
+		t.Fatal(err)
+	}
+	return &wsTestClient{conn: conn, reader: reader}, response
+}
+
+func (this *wsTestClient) writeFrame(fin bool, opcode int, payload []byte, masked bool) {
+	first := byte(opcode)
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first, byte(len(payload))}
+	if masked {
+		mask := []byte{1, 2, 3, 4}
+		frame[1] |= 0x80
+		frame = append(frame, mask...)
+		for i, value := range payload {
+			frame = append(frame, value^mask[i&3])
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+	this.conn.Write(frame)
+}
+
+func (this *wsTestClient) readFrame(t *testing.T) (bool, int, []byte) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(this.reader, header); nil != err {
+		t.Fatal(err)
+	}
+	length := int(header[1] & 0x7f)
+	if length == 126 {
+		extended := make([]byte, 2)
+		io.ReadFull(this.reader, extended)
+		length = int(binary.BigEndian.Uint16(extended))
+	}
+	payload := make([]byte, length)
+	io.ReadFull(this.reader, payload)
+	return header[0]&0x80 != 0, int(header[0] & 0x0f), payload
+}
+
+func newWSServer(upgrader *WSUpgrader, closed chan<- error) *httptest.Server {
+	engine := CreateEngine()
+	echo := func(context *Context, conn *WSConn) {
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if nil != err {
+				closed <- err
+				return
+			}
+			conn.WriteMessage(messageType, data)
+		}
+	}
+	engine.WS("/echo", echo)
+	engine.GET("/custom", upgrader.Handler(echo))
+	return httptest.NewServer(engine)
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	closed := make(chan error, 1)
+	server := newWSServer(&WSUpgrader{}, closed)
+	defer server.Close()
+
+	client, response := dialWS(t, server, "/echo", nil)
+	defer client.conn.Close()
+	if response.StatusCode != http.StatusSwitchingProtocols ||
+		response.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected handshake %d %v", response.StatusCode, response.Header)
+	}
+
+	client.writeFrame(true, WSTextMessage, []byte("hello"), true)
+	if fin, opcode, payload := client.readFrame(t); !fin || opcode != WSTextMessage || string(payload) != "hello" {
+		t.Fatalf("unexpected echo %v %d %q", fin, opcode, payload)
+	}
+
+	//分片消息中间插入 ping, 先收到 pong 再收到合并后的消息
+	client.writeFrame(false, WSBinaryMessage, []byte("ab"), true)
+	client.writeFrame(true, WSPingMessage, []byte("p"), true)
+	client.writeFrame(false, wsContinuation, []byte("cd"), true)
+	client.writeFrame(true, wsContinuation, []byte("ef"), true)
+	if _, opcode, payload := client.readFrame(t); opcode != WSPongMessage || string(payload) != "p" {
+		t.Fatalf("expected pong, got %d %q", opcode, payload)
+	}
+	if _, opcode, payload := client.readFrame(t); opcode != WSBinaryMessage || string(payload) != "abcdef" {
+		t.Fatalf("expected reassembled message, got %d %q", opcode, payload)
+	}
+
+	client.writeFrame(true, WSCloseMessage, []byte{0x03, 0xe8, 'b', 'y', 'e'}, true)
+	if _, opcode, payload := client.readFrame(t); opcode != WSCloseMessage || binary.BigEndian.Uint16(payload) != WSCloseNormalClosure {
+		t.Fatalf("expected close echo, got %d %v", opcode, payload)
+	}
+	err := <-closed
+	if closeError, ok := err.(*WSCloseError); !ok || closeError.Code != WSCloseNormalClosure || closeError.Text != "bye" {
+		t.Fatalf("handler should see the close, got %v", err)
+	}
+}
+
+func TestWebSocketProtocolErrors(t *testing.T) {
+	closed := make(chan error, 1)
+	server := newWSServer(&WSUpgrader{ReadLimit: 4}, closed)
+	defer server.Close()
+
+	cases := []struct {
+		name string
+		send func(client *wsTestClient)
+		code uint16
+	}{
+		{"unmasked", func(client *wsTestClient) {
+			client.writeFrame(true, WSTextMessage, []byte("hi"), false)
+		}, WSCloseProtocolError},
+		{"too big", func(client *wsTestClient) {
+			client.writeFrame(true, WSTextMessage, []byte("hello"), true)
+		}, WSCloseMessageTooBig},
+		{"invalid utf-8", func(client *wsTestClient) {
+			client.writeFrame(true, WSTextMessage, []byte{0xff}, true)
+		}, WSCloseInvalidFramePayloadData},
+		{"orphan continuation", func(client *wsTestClient) {
+			client.writeFrame(true, wsContinuation, []byte("x"), true)
+		}, WSCloseProtocolError},
+	}
+	for _, item := range cases {
+		client, _ := dialWS(t, server, "/custom", nil)
+		item.send(client)
+		if _, opcode, payload := client.readFrame(t); opcode != WSCloseMessage || binary.BigEndian.Uint16(payload) != item.code {
+			t.Errorf("%s: expected close %d, got %d %v", item.name, item.code, opcode, payload)
+		}
+		<-closed
+		client.conn.Close()
+	}
+}
+
+func TestWebSocketHandshake(t *testing.T) {
+	closed := make(chan error, 1)
+	server := newWSServer(&WSUpgrader{Subprotocols: []string{"chat"}, WriteFragmentSize: 3}, closed)
+	defer server.Close()
+
+	if response, err := http.Get(server.URL + "/echo"); nil != err || response.StatusCode != http.StatusBadRequest {
+		t.Fatalf("plain GET should be rejected, got %v", response)
+	}
+	if _, response := dialWS(t, server, "/echo", map[string]string{"Origin": "http://evil.example"}); response.StatusCode != http.StatusForbidden {
+		t.Fatalf("cross origin should be rejected, got %d", response.StatusCode)
+	}
+
+	client, response := dialWS(t, server, "/custom", map[string]string{"Sec-WebSocket-Protocol": "v2, chat"})
+	defer client.conn.Close()
+	if response.Header.Get("Sec-WebSocket-Protocol") != "chat" {
+		t.Fatalf("subprotocol should be negotiated, got %v", response.Header)
+	}
+	//超过 WriteFragmentSize 的消息被拆成多个帧
+	client.writeFrame(true, WSTextMessage, []byte("abcdefg"), true)
+	var frames []string
+	for {
+		fin, _, payload := client.readFrame(t)
+		frames = append(frames, string(payload))
+		if fin {
+			break
+		}
+	}
+	if strings.Join(frames, "|") != "abc|def|g" {
+		t.Fatalf("unexpected fragments %v", frames)
+	}
+}
+
+func TestWebSocketWithoutHijacker(t *testing.T) {
+	engine := CreateEngine()
+	engine.WS("/echo", func(context *Context, conn *WSConn) {
+		t.Fatal("handler should not run without an upgraded connection")
+	})
+
+	request := httptest.NewRequest("GET", "/echo", nil)
+	request.Header.Set("Connection", "Upgrade")
+	request.Header.Set("Upgrade", "websocket")
+	request.Header.Set("Sec-WebSocket-Version", "13")
+	request.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	//ResponseRecorder 不支持 Hijack
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusInternalServerError || recorder.Body.Len() == 0 {
+		t.Fatalf("failed upgrade should answer 500, got %d %q", recorder.Code, recorder.Body.String())
+	}
+}
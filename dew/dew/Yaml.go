@@ -0,0 +1,235 @@
+package dew
+
+import (
+	"encoding"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//encodeYAML 以块格式输出 YAML, 结构体字段名依次取 yaml, json 标签, 最后使用字段名
+func encodeYAML(writer io.Writer, data interface{}) error {
+	lines, inline, err := yamlNode(reflect.ValueOf(data))
+	if nil != err {
+		return err
+	}
+	if nil == lines {
+		_, err = io.WriteString(writer, inline+"\n")
+		return err
+	}
+	_, err = io.WriteString(writer, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+//yamlNode 标量和空容器返回 inline, 其余返回相对缩进为 0 的多行
+func yamlNode(value reflect.Value) ([]string, string, error) {
+	if !value.IsValid() {
+		return nil, "null", nil
+	}
+	if value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil, "null", nil
+		}
+		if value.Type().Implements(textMarshalerType) {
+			return yamlText(value)
+		}
+		return yamlNode(value.Elem())
+	}
+
+	if value.Type() == timeType {
+		return nil, value.Interface().(time.Time).Format(time.RFC3339Nano), nil
+	}
+	if value.Type().Implements(textMarshalerType) {
+		return yamlText(value)
+	}
+
+	switch value.Kind() {
+	case reflect.Bool:
+		return nil, strconv.FormatBool(value.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return nil, strconv.FormatInt(value.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return nil, strconv.FormatUint(value.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		f := value.Float()
+		switch {
+		case math.IsNaN(f):
+			return nil, ".nan", nil
+		case math.IsInf(f, 1):
+			return nil, ".inf", nil
+		case math.IsInf(f, -1):
+			return nil, "-.inf", nil
+		}
+		return nil, strconv.FormatFloat(f, 'g', -1, value.Type().Bits()), nil
+	case reflect.String:
+		return nil, yamlString(value.String()), nil
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return nil, "[]", nil
+		}
+		if value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8 {
+			return nil, "!!binary " + base64.StdEncoding.EncodeToString(value.Bytes()), nil
+		}
+		return yamlSequence(value)
+	case reflect.Map:
+		return yamlMap(value)
+	case reflect.Struct:
+		return yamlStruct(value)
+	}
+	return nil, "", fmt.Errorf("dew: yaml: unsupported type %s", value.Type())
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+func yamlText(value reflect.Value) ([]string, string, error) {
+	text, err := value.Interface().(encoding.TextMarshaler).MarshalText()
+	if nil != err {
+		return nil, "", err
+	}
+	return nil, yamlString(string(text)), nil
+}
+
+func yamlSequence(value reflect.Value) ([]string, string, error) {
+	if value.Len() == 0 {
+		return nil, "[]", nil
+	}
+	lines := make([]string, 0, value.Len())
+	for i := 0; i < value.Len(); i++ {
+		children, inline, err := yamlNode(value.Index(i))
+		if nil != err {
+			return nil, "", err
+		}
+		if nil == children {
+			lines = append(lines, "- "+inline)
+			continue
+		}
+		lines = append(lines, "- "+children[0])
+		for _, line := range children[1:] {
+			lines = append(lines, "  "+line)
+		}
+	}
+	return lines, "", nil
+}
+
+//yamlEntry 把 key: value 追加到 lines, 嵌套内容缩进两格
+func yamlEntry(lines []string, key string, value reflect.Value) ([]string, error) {
+	children, inline, err := yamlNode(value)
+	if nil != err {
+		return nil, err
+	}
+	if nil == children {
+		return append(lines, key+": "+inline), nil
+	}
+	lines = append(lines, key+":")
+	for _, line := range children {
+		lines = append(lines, "  "+line)
+	}
+	return lines, nil
+}
+
+func yamlMap(value reflect.Value) ([]string, string, error) {
+	if value.Len() == 0 {
+		return nil, "{}", nil
+	}
+	keys := value.MapKeys()
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = fmt.Sprint(key.Interface())
+	}
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return names[order[i]] < names[order[j]] })
+
+	lines := make([]string, 0, len(keys))
+	for _, i := range order {
+		var err error
+		if lines, err = yamlEntry(lines, yamlString(names[i]), value.MapIndex(keys[i])); nil != err {
+			return nil, "", err
+		}
+	}
+	return lines, "", nil
+}
+
+func yamlStruct(value reflect.Value) ([]string, string, error) {
+	lines := make([]string, 0, value.NumField())
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		tag, ok := field.Tag.Lookup("yaml")
+		if !ok {
+			tag = field.Tag.Get("json")
+		}
+		name, options := tag, ""
+		if index := strings.IndexByte(tag, ','); index >= 0 {
+			name, options = tag[:index], tag[index+1:]
+		}
+		if name == "-" {
+			continue
+		}
+
+		fieldValue := value.Field(i)
+		//未命名的嵌入结构体字段提升到当前层级
+		if name == "" && field.Anonymous && fieldValue.Kind() == reflect.Struct {
+			children, _, err := yamlStruct(fieldValue)
+			if nil != err {
+				return nil, "", err
+			}
+			lines = append(lines, children...)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if strings.Contains(options, "omitempty") && fieldValue.IsZero() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		var err error
+		if lines, err = yamlEntry(lines, yamlString(name), fieldValue); nil != err {
+			return nil, "", err
+		}
+	}
+	if len(lines) == 0 {
+		return nil, "{}", nil
+	}
+	return lines, "", nil
+}
+
+//yamlString 在字符串可能被解析成其他类型或包含特殊字符时加双引号
+func yamlString(s string) string {
+	if s == "" {
+		return `""`
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "y", "n", "null", "~", ".nan", ".inf", "-.inf":
+		return strconv.Quote(s)
+	}
+	if _, err := strconv.ParseFloat(s, 64); nil == err {
+		return strconv.Quote(s)
+	}
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@` ") || strings.HasSuffix(s, " ") ||
+		strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return strconv.Quote(s)
+	}
+	for _, r := range s {
+		if r < ' ' || r == 0x7f {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
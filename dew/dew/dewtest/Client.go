@@ -0,0 +1,288 @@
+package dewtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type (
+	//Client 向 handler 发送请求的测试客户端, 请求在当前 goroutine 中同步执行
+	Client struct {
+		t       testing.TB
+		handler http.Handler
+		//header 每个请求都带上的头
+		header http.Header
+		//keepCookies 为 true 时保存响应中的 cookie 并在之后的请求中发送, 类似浏览器
+		keepCookies bool
+		cookies     map[string]*http.Cookie
+	}
+
+	//Request 以链式调用构造的请求, Do 或 Expect 时才真正发送
+	Request struct {
+		client  *Client
+		method  string
+		target  string
+		header  http.Header
+		query   url.Values
+		cookies []*http.Cookie
+		body    []byte
+	}
+
+	//Response 请求的结果, 断言失败时以 t.Errorf 报告并返回自身, 便于继续链式断言
+	Response struct {
+		t        testing.TB
+		Recorder *httptest.ResponseRecorder
+	}
+)
+
+func New(t testing.TB, handler http.Handler) *Client {
+	return &Client{
+		t:       t,
+		handler: handler,
+		header:  make(http.Header),
+		cookies: make(map[string]*http.Cookie),
+	}
+}
+
+//WithHeader 设置之后所有请求都带上的头
+func (this *Client) WithHeader(key, value string) *Client {
+	this.header.Set(key, value)
+	return this
+}
+
+//KeepCookies 保存响应设置的 cookie, 用于测试会话和 CSRF 等依赖 cookie 的流程
+func (this *Client) KeepCookies() *Client {
+	this.keepCookies = true
+	return this
+}
+
+func (this *Client) Request(method, target string) *Request {
+	return &Request{
+		client: this,
+		method: method,
+		target: target,
+		header: make(http.Header),
+		query:  make(url.Values),
+	}
+}
+
+func (this *Client) Get(target string) *Request {
+	return this.Request(http.MethodGet, target)
+}
+
+func (this *Client) Head(target string) *Request {
+	return this.Request(http.MethodHead, target)
+}
+
+func (this *Client) Post(target string) *Request {
+	return this.Request(http.MethodPost, target)
+}
+
+func (this *Client) Put(target string) *Request {
+	return this.Request(http.MethodPut, target)
+}
+
+func (this *Client) Patch(target string) *Request {
+	return this.Request(http.MethodPatch, target)
+}
+
+func (this *Client) Delete(target string) *Request {
+	return this.Request(http.MethodDelete, target)
+}
+
+func (this *Client) Options(target string) *Request {
+	return this.Request(http.MethodOptions, target)
+}
+
+func (this *Request) WithHeader(key, value string) *Request {
+	this.header.Set(key, value)
+	return this
+}
+
+//WithQuery 追加查询参数
+func (this *Request) WithQuery(key, value string) *Request {
+	this.query.Add(key, value)
+	return this
+}
+
+func (this *Request) WithCookie(cookie *http.Cookie) *Request {
+	this.cookies = append(this.cookies, cookie)
+	return this
+}
+
+func (this *Request) WithBasicAuth(user, password string) *Request {
+	request := http.Request{Header: this.header}
+	request.SetBasicAuth(user, password)
+	return this
+}
+
+//WithBody 以指定的类型发送 body
+func (this *Request) WithBody(contentType string, body io.Reader) *Request {
+	data, err := ioutil.ReadAll(body)
+	if nil != err {
+		this.client.t.Fatalf("dewtest: read body: %v", err)
+	}
+	this.body = data
+	this.header.Set("Content-Type", contentType)
+	return this
+}
+
+//WithJSON 以 JSON 编码 value 作为 body
+func (this *Request) WithJSON(value interface{}) *Request {
+	data, err := json.Marshal(value)
+	if nil != err {
+		this.client.t.Fatalf("dewtest: encode json: %v", err)
+	}
+	return this.WithBody("application/json", bytes.NewReader(data))
+}
+
+//WithForm 以 application/x-www-form-urlencoded 发送表单
+func (this *Request) WithForm(form url.Values) *Request {
+	return this.WithBody("application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+}
+
+//build 合并客户端和请求上的设置, 生成 *http.Request
+func (this *Request) build() *http.Request {
+	target := this.target
+	if len(this.query) > 0 {
+		separator := "?"
+		if strings.Contains(target, "?") {
+			separator = "&"
+		}
+		target += separator + this.query.Encode()
+	}
+	var body io.Reader
+	if nil != this.body {
+		body = bytes.NewReader(this.body)
+	}
+	request := httptest.NewRequest(this.method, target, body)
+	for key, values := range this.client.header {
+		request.Header[key] = append([]string(nil), values...)
+	}
+	for key, values := range this.header {
+		request.Header[key] = values
+	}
+	for _, cookie := range this.client.cookies {
+		request.AddCookie(cookie)
+	}
+	for _, cookie := range this.cookies {
+		request.AddCookie(cookie)
+	}
+	return request
+}
+
+//Do 发送请求
+func (this *Request) Do() *Response {
+	recorder := httptest.NewRecorder()
+	this.client.handler.ServeHTTP(recorder, this.build())
+	if this.client.keepCookies {
+		for _, cookie := range recorder.Result().Cookies() {
+			if cookie.MaxAge < 0 {
+				delete(this.client.cookies, cookie.Name)
+			} else {
+				this.client.cookies[cookie.Name] = cookie
+			}
+		}
+	}
+	return &Response{t: this.client.t, Recorder: recorder}
+}
+
+//Expect 发送请求并断言状态码
+func (this *Request) Expect(code int) *Response {
+	this.client.t.Helper()
+	return this.Do().Status(code)
+}
+
+func (this *Response) Code() int {
+	return this.Recorder.Code
+}
+
+func (this *Response) BodyString() string {
+	return this.Recorder.Body.String()
+}
+
+func (this *Response) Status(code int) *Response {
+	this.t.Helper()
+	if this.Recorder.Code != code {
+		this.t.Errorf("dewtest: expected status %d, got %d with body %q", code, this.Recorder.Code, this.BodyString())
+	}
+	return this
+}
+
+//Header 断言响应头的值, value 为空时断言没有该头
+func (this *Response) Header(key, value string) *Response {
+	this.t.Helper()
+	if actual := this.Recorder.Header().Get(key); actual != value {
+		this.t.Errorf("dewtest: expected header %s %q, got %q", key, value, actual)
+	}
+	return this
+}
+
+func (this *Response) Body(expected string) *Response {
+	this.t.Helper()
+	if actual := this.BodyString(); actual != expected {
+		this.t.Errorf("dewtest: expected body %q, got %q", expected, actual)
+	}
+	return this
+}
+
+func (this *Response) BodyContains(expected string) *Response {
+	this.t.Helper()
+	if actual := this.BodyString(); !strings.Contains(actual, expected) {
+		this.t.Errorf("dewtest: expected body to contain %q, got %q", expected, actual)
+	}
+	return this
+}
+
+//JSONBody 按 JSON 语义比较响应体, 忽略字段顺序和空白
+func (this *Response) JSONBody(expected interface{}) *Response {
+	this.t.Helper()
+	data, err := json.Marshal(expected)
+	if nil != err {
+		this.t.Fatalf("dewtest: encode json: %v", err)
+	}
+	var want, got interface{}
+	json.Unmarshal(data, &want)
+	if err := json.Unmarshal(this.Recorder.Body.Bytes(), &got); nil != err {
+		this.t.Errorf("dewtest: response is not json: %v, body %q", err, this.BodyString())
+		return this
+	}
+	if !reflect.DeepEqual(want, got) {
+		this.t.Errorf("dewtest: expected json %s, got %s", data, this.BodyString())
+	}
+	return this
+}
+
+//DecodeJSON 把响应体解码到 value, 用于需要进一步检查的场景
+func (this *Response) DecodeJSON(value interface{}) *Response {
+	this.t.Helper()
+	if err := json.Unmarshal(this.Recorder.Body.Bytes(), value); nil != err {
+		this.t.Errorf("dewtest: decode json: %v, body %q", err, this.BodyString())
+	}
+	return this
+}
+
+//Cookie 返回响应设置的 cookie, 没有时为 nil
+func (this *Response) Cookie(name string) *http.Cookie {
+	for _, cookie := range this.Recorder.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+//Golden 把响应体与 testdata/<name>.golden 比较
+func (this *Response) Golden(name string) *Response {
+	this.t.Helper()
+	AssertGolden(this.t, name, this.Recorder.Body.Bytes())
+	return this
+}
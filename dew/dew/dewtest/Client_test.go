@@ -0,0 +1,83 @@
+package dewtest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"dew/dew"
+	"dew/dew/dewtest"
+)
+
+type book struct {
+	Title string  `json:"title" form:"title" binding:"required"`
+	Price float64 `json:"price" form:"price"`
+}
+
+func TestCreateTestContext(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	context, engine := dewtest.CreateTestContext(recorder)
+	context.Request = httptest.NewRequest("GET", "/books/7?sort=price", nil)
+
+	//直接调用处理器
+	handler := func(context *dew.Context) {
+		context.WriteJson(http.StatusOK, dew.H{"sort": context.Query("sort"), "id": context.Param("id")})
+	}
+	handler(context)
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "{\"id\":\"\",\"sort\":\"price\"}\n" {
+		t.Fatalf("unexpected response %d %q", recorder.Code, recorder.Body.String())
+	}
+
+	//经过路由时可以拿到路由参数
+	engine.GET("/books/:id", handler)
+	recorder = httptest.NewRecorder()
+	context = dewtest.CreateTestContextOnly(recorder, engine)
+	context.Request = httptest.NewRequest("GET", "/books/7", nil)
+	engine.HandleContext(context)
+	if recorder.Body.String() != "{\"id\":\"7\",\"sort\":\"\"}\n" {
+		t.Fatalf("unexpected routed response %q", recorder.Body.String())
+	}
+}
+
+func TestRequestBuilder(t *testing.T) {
+	engine := dew.CreateEngine()
+	engine.POST("/books", func(context *dew.Context) {
+		var item book
+		if err := context.Bind(&item); nil != err {
+			context.Fail(http.StatusBadRequest, err)
+			return
+		}
+		context.SetHeader("X-Trace", context.Request.Header.Get("X-Trace"))
+		context.WriteJson(http.StatusCreated, dew.H{"book": item, "lang": context.Query("lang")})
+	})
+	client := dewtest.New(t, engine).WithHeader("X-Trace", "abc")
+
+	client.Post("/books").WithQuery("lang", "zh").WithJSON(book{Title: "Go", Price: 9.5}).
+		Expect(http.StatusCreated).
+		Header("X-Trace", "abc").
+		JSONBody(dew.H{"book": dew.H{"price": 9.5, "title": "Go"}, "lang": "zh"})
+
+	client.Post("/books").WithForm(url.Values{"title": {"Dew"}, "price": {"1"}}).
+		Expect(http.StatusCreated).
+		JSONBody(dew.H{"book": dew.H{"price": 1, "title": "Dew"}, "lang": ""})
+
+	var failure struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	client.Post("/books").WithJSON(dew.H{}).Expect(http.StatusBadRequest).DecodeJSON(&failure)
+	if failure.Message != "validation failed" {
+		t.Fatalf("unexpected failure %+v", failure)
+	}
+
+	client.Get("/books").Expect(http.StatusMethodNotAllowed).Header("Allow", "OPTIONS, POST")
+}
+
+func TestGolden(t *testing.T) {
+	engine := dew.CreateEngine()
+	engine.GET("/catalog", func(context *dew.Context) {
+		context.WriteIndentedJson(http.StatusOK, dew.H{"books": []book{{"Go", 9.5}, {"Dew", 1}}})
+	})
+	dewtest.New(t, engine).Get("/catalog").Expect(http.StatusOK).Golden("catalog")
+}
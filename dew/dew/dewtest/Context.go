@@ -0,0 +1,21 @@
+//Package dewtest 提供不启动服务器即可测试 dew 处理器和中间件的工具
+package dewtest
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"dew/dew"
+)
+
+//CreateTestContext 创建一个新的 Engine 和关联到它的 Context, 请求默认为 GET /
+//可以修改 context.Request 后直接调用处理器, 或交给 engine.HandleContext 走完整的路由和中间件
+func CreateTestContext(writer http.ResponseWriter) (*dew.Context, *dew.Engine) {
+	engine := dew.CreateEngine()
+	return engine.CreateContext(writer, httptest.NewRequest("GET", "/", nil)), engine
+}
+
+//CreateTestContextOnly 为已有的 Engine 创建 Context
+func CreateTestContextOnly(writer http.ResponseWriter, engine *dew.Engine) *dew.Context {
+	return engine.CreateContext(writer, httptest.NewRequest("GET", "/", nil))
+}
@@ -0,0 +1,138 @@
+package dewtest_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"dew/dew"
+	"dew/dew/dewtest"
+)
+
+func TestContextRequestAccessors(t *testing.T) {
+	engine := dew.CreateEngine()
+	engine.POST("/shops/:shop/books/*path", func(context *dew.Context) {
+		context.WriteJson(http.StatusOK, dew.H{
+			"shop":  context.Param("shop"),
+			"path":  context.Param("path"),
+			"query": context.Query("q"),
+			"form":  context.PostForm("title"),
+			"ip":    context.ClientIP(),
+		})
+	})
+	dewtest.New(t, engine).Post("/shops/1/books/a/b").WithQuery("q", "go").
+		WithBody("application/x-www-form-urlencoded", strings.NewReader("title=dew")).
+		Expect(http.StatusOK).
+		JSONBody(dew.H{"shop": "1", "path": "a/b", "query": "go", "form": "dew", "ip": "192.0.2.1"})
+}
+
+func TestContextKeys(t *testing.T) {
+	context, _ := dewtest.CreateTestContext(httptest.NewRecorder())
+	now := time.Now()
+	context.Set("string", "s")
+	context.Set("bool", true)
+	context.Set("int", 1)
+	context.Set("int64", int64(2))
+	context.Set("uint", uint(3))
+	context.Set("float", 4.5)
+	context.Set("time", now)
+	context.Set("duration", time.Second)
+	context.Set("slice", []string{"a"})
+	context.Set("map", map[string]interface{}{"k": 1})
+	context.Set("mapString", map[string]string{"k": "v"})
+
+	if context.GetString("string") != "s" || !context.GetBool("bool") || context.GetInt("int") != 1 ||
+		context.GetInt64("int64") != 2 || context.GetUint("uint") != 3 || context.GetFloat64("float") != 4.5 ||
+		!context.GetTime("time").Equal(now) || context.GetDuration("duration") != time.Second ||
+		context.GetStringSlice("slice")[0] != "a" || context.GetStringMap("map")["k"] != 1 ||
+		context.GetStringMapString("mapString")["k"] != "v" {
+		t.Fatal("typed getters should return the stored values")
+	}
+	if context.GetString("missing") != "" || context.GetInt("string") != 0 {
+		t.Fatal("missing or mistyped keys should return zero values")
+	}
+	if context.Value("string") != "s" {
+		t.Fatal("context.Context Value should read Keys")
+	}
+	copied := context.Copy()
+	copied.Set("string", "changed")
+	if context.GetString("string") != "s" {
+		t.Fatal("Copy should not share Keys")
+	}
+	defer func() {
+		if nil == recover() {
+			t.Fatal("MustGet should panic on missing keys")
+		}
+	}()
+	context.MustGet("missing")
+}
+
+func TestContextChainControl(t *testing.T) {
+	engine := dew.CreateEngine()
+	var trace []string
+	engine.Use(func(context *dew.Context) {
+		trace = append(trace, "outer before")
+		context.Next()
+		trace = append(trace, "outer after")
+	})
+	engine.GET("/abort", func(context *dew.Context) {
+		context.AbortWithError(http.StatusPaymentRequired, errors.New("pay first")).SetType(dew.ErrorTypePublic)
+		if !context.IsAborted() {
+			t.Error("context should be aborted")
+		}
+		context.WriteString(http.StatusPaymentRequired, context.Errors.ByType(dew.ErrorTypePublic).String())
+	}, func(context *dew.Context) {
+		trace = append(trace, "unreachable")
+	})
+
+	dewtest.New(t, engine).Get("/abort").Expect(http.StatusPaymentRequired).BodyContains("pay first")
+	if strings.Join(trace, ",") != "outer before,outer after" {
+		t.Fatalf("unexpected trace %v", trace)
+	}
+}
+
+func TestContextRenderers(t *testing.T) {
+	engine := dew.CreateEngine()
+	data := dew.H{"name": "dew"}
+	engine.GET("/json", func(context *dew.Context) { context.WriteJson(http.StatusOK, data) })
+	engine.GET("/indented", func(context *dew.Context) { context.WriteIndentedJson(http.StatusOK, data) })
+	engine.GET("/secure", func(context *dew.Context) { context.WriteSecureJson(http.StatusOK, []int{1}) })
+	engine.GET("/jsonp", func(context *dew.Context) { context.WriteJsonp(http.StatusOK, data) })
+	engine.GET("/xml", func(context *dew.Context) { context.WriteXML(http.StatusOK, data) })
+	engine.GET("/yaml", func(context *dew.Context) { context.WriteYAML(http.StatusOK, data) })
+	engine.GET("/data", func(context *dew.Context) { context.WriteData(http.StatusOK, []byte("raw")) })
+	engine.GET("/string", func(context *dew.Context) { context.WriteString(http.StatusOK, "hi %s", "dew") })
+	engine.GET("/reader", func(context *dew.Context) {
+		context.WriteReader(http.StatusOK, 3, "text/plain", strings.NewReader("abc"), map[string]string{"X-From": "reader"})
+	})
+	engine.GET("/negotiate", func(context *dew.Context) { context.Negotiate(http.StatusOK, data) })
+	engine.GET("/stream", func(context *dew.Context) {
+		count := 0
+		context.Stream(func(writer io.Writer) bool {
+			count++
+			io.WriteString(writer, "x")
+			return count < 2
+		})
+	})
+	engine.GET("/sse", func(context *dew.Context) { context.SSEvent("ping", "1") })
+
+	client := dewtest.New(t, engine)
+	client.Get("/json").Expect(http.StatusOK).JSONBody(data).Header("Content-Type", "application/json")
+	client.Get("/indented").Expect(http.StatusOK).Body("{\n    \"name\": \"dew\"\n}\n")
+	client.Get("/secure").Expect(http.StatusOK).Body("while(1);[1]\n")
+	client.Get("/jsonp").WithQuery("callback", "cb").Expect(http.StatusOK).Body(`cb({"name":"dew"});`)
+	client.Get("/jsonp").WithQuery("callback", "alert(1)//").Expect(http.StatusBadRequest)
+	client.Get("/xml").Expect(http.StatusOK).Body("<map><name>dew</name></map>")
+	client.Get("/yaml").Expect(http.StatusOK).Body("name: dew\n")
+	client.Get("/data").Expect(http.StatusOK).Body("raw")
+	client.Get("/string").Expect(http.StatusOK).Body("hi dew")
+	client.Get("/reader").Expect(http.StatusOK).Body("abc").Header("X-From", "reader")
+	client.Get("/negotiate").WithHeader("Accept", "application/x-yaml").Expect(http.StatusOK).Body("name: dew\n")
+	client.Get("/negotiate").WithHeader("Accept", "image/png").Expect(http.StatusNotAcceptable)
+	client.Get("/stream").Expect(http.StatusOK).Body("xx")
+	client.Get("/sse").Expect(http.StatusOK).Body("event: ping\ndata: 1\n\n").Header("Content-Type", "text/event-stream")
+}
@@ -0,0 +1,39 @@
+package dewtest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+//EnvUpdateGolden 该环境变量不为空时写入 golden 文件而不是比较, 例如 DEWTEST_UPDATE=1 go test ./...
+const EnvUpdateGolden = "DEWTEST_UPDATE"
+
+//GoldenPath golden 文件的位置, 相对于测试所在的包目录
+func GoldenPath(name string) string {
+	return filepath.Join("testdata", name+".golden")
+}
+
+//AssertGolden 比较 actual 与 golden 文件的内容, 需要更新时设置 EnvUpdateGolden
+func AssertGolden(t testing.TB, name string, actual []byte) {
+	t.Helper()
+	path := GoldenPath(name)
+	if os.Getenv(EnvUpdateGolden) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); nil != err {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, actual, 0644); nil != err {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := ioutil.ReadFile(path)
+	if nil != err {
+		t.Fatalf("dewtest: %v, run with %s=1 to create it", err, EnvUpdateGolden)
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("dewtest: %s mismatch\nexpected:\n%s\nactual:\n%s", path, expected, actual)
+	}
+}
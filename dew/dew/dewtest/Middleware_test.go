@@ -0,0 +1,124 @@
+package dewtest_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"dew/dew"
+	"dew/dew/dewtest"
+	"dew/dew/sessions"
+)
+
+func TestLoggerAndRecovery(t *testing.T) {
+	var output bytes.Buffer
+	engine := dew.CreateEngine()
+	engine.Use(
+		dew.LoggerWithConfig(dew.LoggerConfig{Output: &output, Formatter: dew.JSONFormatter}),
+		dew.RecoveryWithWriter(ioutil.Discard),
+	)
+	engine.GET("/panic", func(context *dew.Context) {
+		panic("boom")
+	})
+
+	response := dewtest.New(t, engine).Get("/panic").WithHeader("X-Request-ID", "req-1").
+		Expect(http.StatusInternalServerError).Header("X-Request-ID", "req-1")
+	if !strings.Contains(response.BodyString(), "Internal Server Error") {
+		t.Errorf("unexpected recovery body %q", response.BodyString())
+	}
+	var params dew.LogParams
+	if err := json.Unmarshal(output.Bytes(), &params); nil != err {
+		t.Fatalf("log line should be json: %v %q", err, output.String())
+	}
+	if params.StatusCode != http.StatusInternalServerError || params.Level != "ERROR" || params.RequestID != "req-1" {
+		t.Fatalf("unexpected log %+v", params)
+	}
+}
+
+func TestCORSAndCompress(t *testing.T) {
+	engine := dew.CreateEngine()
+	engine.Use(dew.CORS(dew.CORSConfig{AllowOrigins: []string{"https://*.example.com"}}), dew.Gzip(gzip.BestSpeed))
+	engine.GET("/books", func(context *dew.Context) {
+		context.WriteString(http.StatusOK, strings.Repeat("book ", 500))
+	})
+
+	client := dewtest.New(t, engine)
+	client.Options("/books").WithHeader("Origin", "https://shop.example.com").
+		WithHeader("Access-Control-Request-Method", "GET").
+		Expect(http.StatusNoContent).
+		Header("Access-Control-Allow-Origin", "https://shop.example.com").
+		Header("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS")
+	client.Options("/books").WithHeader("Origin", "https://evil.com").
+		WithHeader("Access-Control-Request-Method", "GET").
+		Expect(http.StatusForbidden)
+
+	response := client.Get("/books").WithHeader("Accept-Encoding", "gzip").
+		Expect(http.StatusOK).Header("Content-Encoding", "gzip")
+	reader, err := gzip.NewReader(response.Recorder.Body)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadAll(reader); len(data) != 2500 {
+		t.Fatalf("unexpected decompressed length %d", len(data))
+	}
+}
+
+func TestRateLimitAndAuth(t *testing.T) {
+	engine := dew.CreateEngine()
+	admin := engine.Group("/admin")
+	admin.Use(dew.RateLimit(dew.RateLimitConfig{Rate: 1, Burst: 2}), dew.BasicAuth(dew.Accounts{"root": "secret"}))
+	admin.GET("", func(context *dew.Context) {
+		context.WriteString(http.StatusOK, context.GetString(dew.AuthUserKey))
+	})
+	api := engine.Group("/api")
+	api.Use(dew.APIKey(dew.APIKeyConfig{Keys: []string{"k1"}}))
+	api.GET("/ping", func(context *dew.Context) {
+		context.WriteString(http.StatusOK, "pong")
+	})
+
+	client := dewtest.New(t, engine)
+	client.Get("/admin").WithBasicAuth("root", "secret").Expect(http.StatusOK).Body("root").
+		Header("X-RateLimit-Remaining", "1")
+	client.Get("/admin").Expect(http.StatusUnauthorized)
+	client.Get("/admin").WithBasicAuth("root", "secret").Expect(http.StatusTooManyRequests).Header("Retry-After", "1")
+
+	client.Get("/api/ping").WithHeader("X-API-Key", "k1").Expect(http.StatusOK).Body("pong")
+	client.Get("/api/ping").Expect(http.StatusUnauthorized)
+	client.Get("/api/ping").WithHeader("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte("root:secret"))).
+		Expect(http.StatusUnauthorized)
+}
+
+func TestSessionsAndCSRF(t *testing.T) {
+	engine := dew.CreateEngine()
+	engine.Use(sessions.Sessions("sid", sessions.MemoryStore(nil)), dew.CSRF(dew.CSRFConfig{}))
+	engine.GET("/login", func(context *dew.Context) {
+		context.WriteString(http.StatusOK, dew.CSRFToken(context))
+	})
+	engine.POST("/login", func(context *dew.Context) {
+		session := sessions.Default(context)
+		session.RenewID()
+		session.Set("user", context.PostForm("user"))
+		context.WriteString(http.StatusOK, "welcome")
+	})
+	engine.GET("/me", func(context *dew.Context) {
+		user, _ := sessions.Default(context).Get("user").(string)
+		context.WriteString(http.StatusOK, user)
+	})
+
+	client := dewtest.New(t, engine).KeepCookies()
+	token := client.Get("/login").Expect(http.StatusOK).BodyString()
+	client.Post("/login").WithForm(url.Values{"user": {"alice"}}).Expect(http.StatusForbidden)
+	response := client.Post("/login").WithForm(url.Values{"user": {"alice"}, "_csrf": {token}}).
+		Expect(http.StatusOK).Body("welcome")
+	if nil == response.Cookie("sid") {
+		t.Fatal("login should set the session cookie")
+	}
+	client.Get("/me").Expect(http.StatusOK).Body("alice")
+	dewtest.New(t, engine).Get("/me").Expect(http.StatusOK).Body("")
+}
@@ -0,0 +1,101 @@
+package dewtest_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"dew/dew"
+	"dew/dew/dewtest"
+)
+
+//tracer 记录中间件的执行顺序
+func tracer(name string) dew.HandlerFunction {
+	return func(context *dew.Context) {
+		context.SetHeader("X-Trace", strings.TrimPrefix(context.Writer.Header().Get("X-Trace")+","+name, ","))
+		context.Next()
+	}
+}
+
+func TestRouterGroupMiddlewares(t *testing.T) {
+	engine := dew.CreateEngine()
+	engine.Use(tracer("engine"))
+	v1 := engine.Group("/v1")
+	v1.Use(tracer("v1"))
+	admin := v1.Group("/admin")
+	admin.GET("/users", tracer("route"), func(context *dew.Context) {
+		context.WriteString(http.StatusOK, "users")
+	})
+	//路由注册之后再 Use 也会生效
+	admin.Use(tracer("admin"))
+	engine.GET("/v1admin", func(context *dew.Context) {
+		context.WriteString(http.StatusOK, "not in group")
+	})
+
+	client := dewtest.New(t, engine)
+	client.Get("/v1/admin/users").Expect(http.StatusOK).Header("X-Trace", "engine,v1,admin,route").Body("users")
+	client.Get("/v1/admin/users/").Expect(http.StatusOK)
+	client.Get("/v1admin").Expect(http.StatusOK).Header("X-Trace", "engine")
+	//未匹配的路由也会经过所在分组的中间件
+	client.Get("/v1/missing").Expect(http.StatusNotFound).Header("X-Trace", "engine,v1")
+}
+
+func TestRouterGroupMethods(t *testing.T) {
+	engine := dew.CreateEngine()
+	echo := func(context *dew.Context) {
+		context.WriteString(http.StatusOK, context.Method)
+	}
+	books := engine.Group("/books")
+	books.GET("", echo)
+	books.POST("", echo)
+	books.PUT("/:id", echo)
+	books.PATCH("/:id", echo)
+	books.DELETE("/:id", echo)
+	books.HEAD("/head", echo)
+	books.OPTIONS("/options", echo)
+	books.Handle("PURGE", "/cache", echo)
+	books.Any("/any", echo)
+
+	client := dewtest.New(t, engine)
+	client.Get("/books").Expect(http.StatusOK).Body("GET")
+	client.Post("/books").Expect(http.StatusOK).Body("POST")
+	client.Put("/books/1").Expect(http.StatusOK).Body("PUT")
+	client.Patch("/books/1").Expect(http.StatusOK).Body("PATCH")
+	client.Delete("/books/1").Expect(http.StatusOK).Body("DELETE")
+	client.Head("/books/head").Expect(http.StatusOK)
+	client.Options("/books/options").Expect(http.StatusOK).Body("OPTIONS")
+	client.Request("PURGE", "/books/cache").Expect(http.StatusOK).Body("PURGE")
+	for _, method := range []string{"GET", "POST", "PUT", "PATCH", "DELETE", "CONNECT", "TRACE"} {
+		client.Request(method, "/books/any").Expect(http.StatusOK).Body(method)
+	}
+
+	//HEAD 回退到 GET, OPTIONS 自动返回 Allow
+	client.Head("/books").Expect(http.StatusOK).Body("HEAD")
+	client.Options("/books/1").Expect(http.StatusNoContent).Header("Allow", "DELETE, OPTIONS, PATCH, PUT")
+	client.Post("/books/1").Expect(http.StatusMethodNotAllowed).Header("Allow", "DELETE, OPTIONS, PATCH, PUT")
+}
+
+func TestNoRouteAndNoMethodHandlers(t *testing.T) {
+	engine := dew.CreateEngine()
+	engine.GET("/only-get", func(context *dew.Context) {})
+	engine.NoRoute(func(context *dew.Context) {
+		context.Fail(http.StatusNotFound, "no such page")
+	})
+	engine.NoMethod(func(context *dew.Context) {
+		context.Fail(http.StatusMethodNotAllowed, "wrong method")
+	})
+
+	client := dewtest.New(t, engine)
+	client.Get("/nothing").Expect(http.StatusNotFound).JSONBody(dew.H{"code": 404, "message": "no such page"})
+	client.Delete("/only-get").Expect(http.StatusMethodNotAllowed).JSONBody(dew.H{"code": 405, "message": "wrong method"})
+}
+
+func TestRoutesHandler(t *testing.T) {
+	engine := dew.CreateEngine()
+	engine.GET("/routes", engine.RoutesHandler())
+	var routes dew.RoutesInfo
+	dewtest.New(t, engine).Get("/routes").Expect(http.StatusOK).DecodeJSON(&routes)
+	if len(routes) != 1 || routes[0].Path != "/routes" || routes[0].Method != "GET" {
+		t.Fatalf("unexpected routes %+v", routes)
+	}
+}
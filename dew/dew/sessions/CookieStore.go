@@ -0,0 +1,140 @@
+package sessions
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrInvalidCookie = errors.New("dew: session cookie is invalid")
+	ErrCookieExpired = errors.New("dew: session cookie is expired")
+	ErrCookieTooLong = errors.New("dew: session cookie exceeds 4096 bytes")
+)
+
+//maxCookieLength 浏览器对单个 cookie 的普遍限制
+const maxCookieLength = 4096
+
+//cookieStore 会话数据保存在 cookie 中, 先以 AES-GCM 加密再以 HMAC-SHA256 签名
+type cookieStore struct {
+	hashKey []byte
+	block   cipher.AEAD
+	options Options
+	now     func() time.Time
+}
+
+//CookieStore hashKey 用于签名, 建议 32 或 64 字节, blockKey 为 16, 24 或 32 字节的 AES 密钥, 为 nil 时只签名不加密
+func CookieStore(hashKey, blockKey []byte, options *Options) Store {
+	if len(hashKey) == 0 {
+		panic("dew: CookieStore requires a hash key")
+	}
+	if nil == options {
+		options = DefaultOptions()
+	}
+	store := &cookieStore{hashKey: hashKey, options: *options, now: time.Now}
+	if nil != blockKey {
+		block, err := aes.NewCipher(blockKey)
+		if nil != err {
+			panic("dew: " + err.Error())
+		}
+		store.block, _ = cipher.NewGCM(block)
+	}
+	return store
+}
+
+func (this *cookieStore) mac(name string, data []byte) []byte {
+	mac := hmac.New(sha256.New, this.hashKey)
+	io.WriteString(mac, name)
+	mac.Write([]byte{0})
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+//encode 格式为 base64url(时间戳 | 密文 | 签名), 签名中包含 cookie 名, 防止不同会话间互换
+func (this *cookieStore) encode(name string, values map[string]interface{}) (string, error) {
+	plain, err := encodeValues(values)
+	if nil != err {
+		return "", err
+	}
+	data := make([]byte, 8, 8+len(plain)+64)
+	binary.BigEndian.PutUint64(data, uint64(this.now().Unix()))
+	if nil != this.block {
+		nonce := make([]byte, this.block.NonceSize())
+		if _, err := rand.Read(nonce); nil != err {
+			return "", err
+		}
+		data = append(data, nonce...)
+		data = this.block.Seal(data, nonce, plain, []byte(name))
+	} else {
+		data = append(data, plain...)
+	}
+	data = append(data, this.mac(name, data)...)
+	value := base64.RawURLEncoding.EncodeToString(data)
+	if len(name)+len(value) > maxCookieLength {
+		return "", ErrCookieTooLong
+	}
+	return value, nil
+}
+
+func (this *cookieStore) decode(name, value string, maxAge int) (map[string]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if nil != err || len(data) < 8+sha256.Size {
+		return nil, ErrInvalidCookie
+	}
+	signed, signature := data[:len(data)-sha256.Size], data[len(data)-sha256.Size:]
+	if !hmac.Equal(this.mac(name, signed), signature) {
+		return nil, ErrInvalidCookie
+	}
+	created := time.Unix(int64(binary.BigEndian.Uint64(signed)), 0)
+	if maxAge > 0 && this.now().Sub(created) > time.Duration(maxAge)*time.Second {
+		return nil, ErrCookieExpired
+	}
+	plain := signed[8:]
+	if nil != this.block {
+		size := this.block.NonceSize()
+		if len(plain) < size {
+			return nil, ErrInvalidCookie
+		}
+		plain, err = this.block.Open(nil, plain[:size], plain[size:], []byte(name))
+		if nil != err {
+			return nil, ErrInvalidCookie
+		}
+	}
+	return decodeValues(plain)
+}
+
+func (this *cookieStore) Load(request *http.Request, name string) (*Session, error) {
+	session := NewSession(name, this.options)
+	cookie, err := request.Cookie(name)
+	if nil != err || cookie.Value == "" {
+		return session, nil
+	}
+	values, err := this.decode(name, cookie.Value, this.options.MaxAge)
+	if nil != err {
+		return session, err
+	}
+	session.Values = values
+	session.isNew = false
+	return session, nil
+}
+
+func (this *cookieStore) Save(writer http.ResponseWriter, request *http.Request, session *Session) error {
+	if session.Options.MaxAge < 0 {
+		http.SetCookie(writer, session.cookie(""))
+		return nil
+	}
+	value, err := this.encode(session.name, session.Values)
+	if nil != err {
+		return err
+	}
+	http.SetCookie(writer, session.cookie(value))
+	return nil
+}
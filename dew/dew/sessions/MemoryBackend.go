@@ -0,0 +1,89 @@
+package sessions
+
+import (
+	"sync"
+	"time"
+)
+
+//sweepInterval 每写入这么多次清理一次过期会话
+const sweepInterval = 1024
+
+type (
+	memoryEntry struct {
+		data    []byte
+		expires time.Time
+	}
+
+	//MemoryBackend 内存中的会话存储, 过期数据在读取时以及定期写入时清理, 只适用于单实例部署
+	MemoryBackend struct {
+		mutex      sync.Mutex
+		entries    map[string]memoryEntry
+		defaultTTL time.Duration
+		writes     int
+		now        func() time.Time
+	}
+)
+
+//NewMemoryBackend defaultTTL 用于没有设置 MaxAge 的会话, 小于等于 0 时使用 24 小时
+func NewMemoryBackend(defaultTTL time.Duration) *MemoryBackend {
+	if defaultTTL <= 0 {
+		defaultTTL = 24 * time.Hour
+	}
+	return &MemoryBackend{
+		entries:    make(map[string]memoryEntry),
+		defaultTTL: defaultTTL,
+		now:        time.Now,
+	}
+}
+
+func (this *MemoryBackend) Read(id string) ([]byte, bool, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	entry, ok := this.entries[id]
+	if !ok {
+		return nil, false, nil
+	}
+	if !this.now().Before(entry.expires) {
+		delete(this.entries, id)
+		return nil, false, nil
+	}
+	return entry.data, true, nil
+}
+
+func (this *MemoryBackend) Write(id string, data []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = this.defaultTTL
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	now := this.now()
+	this.writes++
+	if this.writes%sweepInterval == 0 {
+		for key, entry := range this.entries {
+			if !now.Before(entry.expires) {
+				delete(this.entries, key)
+			}
+		}
+	}
+	this.entries[id] = memoryEntry{data: data, expires: now.Add(ttl)}
+	return nil
+}
+
+func (this *MemoryBackend) Delete(id string) error {
+	this.mutex.Lock()
+	delete(this.entries, id)
+	this.mutex.Unlock()
+	return nil
+}
+
+//Len 当前保存的会话数量, 包括尚未清理的过期会话
+func (this *MemoryBackend) Len() int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return len(this.entries)
+}
+
+//MemoryStore 以 MemoryBackend 为后端的 ServerStore
+func MemoryStore(options *Options) Store {
+	return ServerStore(NewMemoryBackend(0), options)
+}
@@ -0,0 +1,182 @@
+package sessions
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"net/http"
+)
+
+//flashPrefix 闪存消息在 Values 中的键前缀
+const flashPrefix = "_flash_"
+
+func init() {
+	gob.Register([]interface{}{})
+}
+
+type (
+	//Options 会话 cookie 的属性, MaxAge 同时决定服务端存储的过期时间
+	Options struct {
+		Path   string
+		Domain string
+		//MaxAge 秒数, 0 表示浏览器会话 cookie, 小于 0 表示立即删除
+		MaxAge   int
+		Secure   bool
+		HttpOnly bool
+		SameSite http.SameSite
+	}
+
+	//Session 一次请求中加载的会话, 值保存时使用 gob 编码, 自定义类型需先 gob.Register
+	Session struct {
+		//ID 服务端存储使用的会话 ID, CookieStore 不使用
+		ID      string
+		Values  map[string]interface{}
+		Options *Options
+		name    string
+		isNew   bool
+		//previousID RenewID 之前的 ID, 保存时从后端删除
+		previousID string
+		modified   bool
+	}
+)
+
+//DefaultOptions 路径为 "/", 30 天过期, HttpOnly, SameSite=Lax
+func DefaultOptions() *Options {
+	return &Options{
+		Path:     "/",
+		MaxAge:   86400 * 30,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+//NewSession 创建一个新会话, 供 Store 实现在没有已有会话时使用
+func NewSession(name string, options Options) *Session {
+	return &Session{
+		ID:      newID(),
+		Values:  make(map[string]interface{}),
+		Options: &options,
+		name:    name,
+		isNew:   true,
+	}
+}
+
+func newID() string {
+	var data [32]byte
+	if _, err := rand.Read(data[:]); nil != err {
+		panic("dew: failed to generate session id: " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(data[:])
+}
+
+func (this *Session) Name() string {
+	return this.name
+}
+
+//IsNew 请求中没有有效的会话 cookie 时为 true
+func (this *Session) IsNew() bool {
+	return this.isNew
+}
+
+//Modified 会话是否需要在响应时保存
+func (this *Session) Modified() bool {
+	return this.modified
+}
+
+func (this *Session) Get(key string) interface{} {
+	return this.Values[key]
+}
+
+func (this *Session) Set(key string, value interface{}) {
+	this.Values[key] = value
+	this.modified = true
+}
+
+func (this *Session) Delete(key string) {
+	delete(this.Values, key)
+	this.modified = true
+}
+
+//Clear 清空所有值, 会话本身保留
+func (this *Session) Clear() {
+	for key := range this.Values {
+		delete(this.Values, key)
+	}
+	this.modified = true
+}
+
+//AddFlash 添加一条闪存消息, group 为空时使用默认分组
+func (this *Session) AddFlash(value interface{}, group ...string) {
+	key := flashPrefix
+	if len(group) > 0 {
+		key += group[0]
+	}
+	flashes, _ := this.Values[key].([]interface{})
+	this.Values[key] = append(flashes, value)
+	this.modified = true
+}
+
+//Flashes 取出并删除闪存消息
+func (this *Session) Flashes(group ...string) []interface{} {
+	key := flashPrefix
+	if len(group) > 0 {
+		key += group[0]
+	}
+	flashes, ok := this.Values[key].([]interface{})
+	if !ok {
+		return nil
+	}
+	delete(this.Values, key)
+	this.modified = true
+	return flashes
+}
+
+//RenewID 更换会话 ID 并保留已有的值, 应在登录等权限变化时调用以防止会话固定攻击
+func (this *Session) RenewID() {
+	if this.previousID == "" && !this.isNew {
+		this.previousID = this.ID
+	}
+	this.ID = newID()
+	this.modified = true
+}
+
+//Destroy 清空会话并在响应时删除 cookie 和服务端数据
+func (this *Session) Destroy() {
+	this.Clear()
+	this.Options.MaxAge = -1
+}
+
+func encodeValues(values map[string]interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(values); nil != err {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func decodeValues(data []byte) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&values); nil != err {
+		return nil, err
+	}
+	return values, nil
+}
+
+//cookie 按 Options 生成 cookie, MaxAge 小于 0 时生成删除 cookie
+func (this *Session) cookie(value string) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     this.name,
+		Value:    value,
+		Path:     this.Options.Path,
+		Domain:   this.Options.Domain,
+		MaxAge:   this.Options.MaxAge,
+		Secure:   this.Options.Secure,
+		HttpOnly: this.Options.HttpOnly,
+		SameSite: this.Options.SameSite,
+	}
+	if cookie.MaxAge < 0 {
+		cookie.Value = ""
+	}
+	return cookie
+}
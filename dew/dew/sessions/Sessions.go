@@ -0,0 +1,80 @@
+//Package sessions 为 dew 提供会话支持, 包括加密 cookie 存储和可接入 SQL 等数据库的服务端存储
+package sessions
+
+import (
+	"dew/dew"
+)
+
+//DefaultKey 当前会话在 Context.Keys 中的键, 具名会话的键为 DefaultKey + "/" + name
+const DefaultKey = "dew/sessions"
+
+//sessionWriter 在响应头第一次写出之前保存会话, 使 Set-Cookie 能随响应发出
+type sessionWriter struct {
+	dew.ResponseWriter
+	save func()
+}
+
+func (this *sessionWriter) Write(data []byte) (int, error) {
+	this.save()
+	return this.ResponseWriter.Write(data)
+}
+
+func (this *sessionWriter) WriteString(s string) (int, error) {
+	this.save()
+	return this.ResponseWriter.WriteString(s)
+}
+
+func (this *sessionWriter) WriteHeaderNow() {
+	this.save()
+	this.ResponseWriter.WriteHeaderNow()
+}
+
+func (this *sessionWriter) Flush() {
+	this.save()
+	this.ResponseWriter.Flush()
+}
+
+//Sessions 加载名为 name 的会话, 会话被修改时在响应前自动保存, 加载和保存的错误记录在 Context.Errors 中
+func Sessions(name string, store Store) dew.HandlerFunction {
+	return dew.NameHandler("dew/dew/sessions.Sessions", func(context *dew.Context) {
+		session, err := store.Load(context.Request, name)
+		if nil != err {
+			context.Error(err)
+		}
+		context.Set(DefaultKey, session)
+		context.Set(DefaultKey+"/"+name, session)
+
+		original := context.Writer
+		saved := false
+		writer := &sessionWriter{ResponseWriter: original}
+		writer.save = func() {
+			if saved || !session.modified {
+				return
+			}
+			saved = true
+			if err := store.Save(original, context.Request, session); nil != err {
+				context.Error(err)
+			}
+		}
+		context.Writer = writer
+		defer func() {
+			context.Writer = original
+		}()
+		context.Next()
+		writer.save()
+	})
+}
+
+//Default 返回最内层 Sessions 中间件加载的会话, 没有时返回 nil
+func Default(context *dew.Context) *Session {
+	value, _ := context.Get(DefaultKey)
+	session, _ := value.(*Session)
+	return session
+}
+
+//Get 返回名为 name 的会话, 用于同时使用多个会话的场景
+func Get(context *dew.Context, name string) *Session {
+	value, _ := context.Get(DefaultKey + "/" + name)
+	session, _ := value.(*Session)
+	return session
+}
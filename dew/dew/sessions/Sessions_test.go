@@ -0,0 +1,168 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"dew/dew"
+)
+
+func newSessionEngine(store Store) *dew.Engine {
+	engine := dew.CreateEngine()
+	engine.Use(Sessions("sid", store))
+	engine.POST("/login", func(context *dew.Context) {
+		session := Default(context)
+		session.RenewID()
+		session.Set("user", context.PostForm("user"))
+		session.AddFlash("welcome")
+		context.WriteString(http.StatusOK, "ok")
+	})
+	engine.GET("/me", func(context *dew.Context) {
+		session := Default(context)
+		user, _ := session.Get("user").(string)
+		flashes := session.Flashes()
+		context.WriteString(http.StatusOK, user+" "+strings.Repeat("*", len(flashes)))
+	})
+	engine.POST("/logout", func(context *dew.Context) {
+		Default(context).Destroy()
+		context.WriteString(http.StatusOK, "bye")
+	})
+	return engine
+}
+
+func serveSession(engine *dew.Engine, method, target string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	var request *http.Request
+	if method == "POST" {
+		request = httptest.NewRequest(method, target, strings.NewReader("user=alice"))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		request = httptest.NewRequest(method, target, nil)
+	}
+	if nil != cookie {
+		request.AddCookie(cookie)
+	}
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func sessionCookie(t *testing.T, recorder *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range recorder.Result().Cookies() {
+		if cookie.Name == "sid" {
+			return cookie
+		}
+	}
+	t.Fatalf("no session cookie in %v", recorder.Header())
+	return nil
+}
+
+func testStoreFlow(t *testing.T, store Store) {
+	engine := newSessionEngine(store)
+	if recorder := serveSession(engine, "GET", "/me", nil); len(recorder.Result().Cookies()) != 0 {
+		t.Fatal("unmodified session should not be saved")
+	}
+
+	cookie := sessionCookie(t, serveSession(engine, "POST", "/login", nil))
+	if !cookie.HttpOnly || cookie.Path != "/" {
+		t.Fatalf("unexpected cookie attributes %+v", cookie)
+	}
+	recorder := serveSession(engine, "GET", "/me", cookie)
+	if recorder.Body.String() != "alice *" {
+		t.Fatalf("expected user and one flash, got %q", recorder.Body.String())
+	}
+	cookie = sessionCookie(t, recorder)
+	if recorder := serveSession(engine, "GET", "/me", cookie); recorder.Body.String() != "alice " {
+		t.Fatalf("flash should be consumed, got %q", recorder.Body.String())
+	}
+
+	expired := sessionCookie(t, serveSession(engine, "POST", "/logout", cookie))
+	if expired.MaxAge >= 0 || expired.Value != "" {
+		t.Fatalf("logout should delete the cookie, got %+v", expired)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	backend := NewMemoryBackend(0)
+	store := ServerStore(backend, nil)
+	testStoreFlow(t, store)
+	if backend.Len() != 0 {
+		t.Fatalf("destroyed session should be removed, %d left", backend.Len())
+	}
+
+	//登录时更换 ID, 旧 ID 随之失效
+	engine := newSessionEngine(store)
+	first := sessionCookie(t, serveSession(engine, "POST", "/login", nil))
+	second := sessionCookie(t, serveSession(engine, "POST", "/login", first))
+	if first.Value == second.Value || backend.Len() != 1 {
+		t.Fatalf("session id should be rotated, %d sessions stored", backend.Len())
+	}
+	if recorder := serveSession(engine, "GET", "/me", first); recorder.Body.String() != " " {
+		t.Fatalf("old session id should be invalid, got %q", recorder.Body.String())
+	}
+}
+
+func TestMemoryBackendTTL(t *testing.T) {
+	backend := NewMemoryBackend(time.Minute)
+	now := time.Unix(0, 0)
+	backend.now = func() time.Time { return now }
+	backend.Write("a", []byte("1"), 0)
+	backend.Write("b", []byte("2"), time.Hour)
+
+	now = now.Add(2 * time.Minute)
+	if _, ok, _ := backend.Read("a"); ok {
+		t.Fatal("entry should expire after the default ttl")
+	}
+	if data, ok, _ := backend.Read("b"); !ok || string(data) != "2" {
+		t.Fatal("entry with a longer ttl should survive")
+	}
+	if backend.Len() != 1 {
+		t.Fatalf("expired entry should be evicted, %d left", backend.Len())
+	}
+}
+
+func TestCookieStore(t *testing.T) {
+	store := CookieStore([]byte("hash-key-for-tests"), []byte("0123456789abcdef"), nil)
+	testStoreFlow(t, store)
+
+	session := NewSession("sid", *DefaultOptions())
+	session.Set("user", "alice")
+	recorder := httptest.NewRecorder()
+	if err := store.Save(recorder, nil, session); nil != err {
+		t.Fatal(err)
+	}
+	cookie := sessionCookie(t, recorder)
+	if strings.Contains(cookie.Value, "alice") {
+		t.Fatal("cookie value should be encrypted")
+	}
+
+	request := httptest.NewRequest("GET", "/", nil)
+	tampered := *cookie
+	middle := len(cookie.Value) / 2
+	replacement := "A"
+	if cookie.Value[middle] == 'A' {
+		replacement = "B"
+	}
+	tampered.Value = cookie.Value[:middle] + replacement + cookie.Value[middle+1:]
+	request.AddCookie(&tampered)
+	if loaded, err := store.Load(request, "sid"); err != ErrInvalidCookie || !loaded.IsNew() {
+		t.Fatalf("tampered cookie should be rejected, got %v", err)
+	}
+
+	//cookie 不能冒充另一个名字的会话
+	request = httptest.NewRequest("GET", "/", nil)
+	request.AddCookie(&http.Cookie{Name: "other", Value: cookie.Value})
+	if _, err := store.Load(request, "other"); err != ErrInvalidCookie {
+		t.Fatalf("cookie should be bound to its name, got %v", err)
+	}
+
+	expiring := store.(*cookieStore)
+	expiring.now = func() time.Time { return time.Now().Add(31 * 24 * time.Hour) }
+	request = httptest.NewRequest("GET", "/", nil)
+	request.AddCookie(cookie)
+	if _, err := store.Load(request, "sid"); err != ErrCookieExpired {
+		t.Fatalf("old cookie should expire, got %v", err)
+	}
+}
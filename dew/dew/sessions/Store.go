@@ -0,0 +1,87 @@
+package sessions
+
+import (
+	"net/http"
+	"time"
+)
+
+type (
+	//Store 负责从请求中加载会话以及把会话写回响应
+	Store interface {
+		//Load 加载名为 name 的会话, 没有或无效时返回新会话, 返回的 error 仅用于记录
+		Load(request *http.Request, name string) (*Session, error)
+		//Save 保存会话并设置 cookie, 必须在响应头写出之前调用
+		Save(writer http.ResponseWriter, request *http.Request, session *Session) error
+	}
+
+	//Backend 服务端会话数据的存储, 实现该接口即可把会话保存到 SQL 等数据库
+	Backend interface {
+		//Read 读取会话数据, 不存在或已过期时 ok 为 false
+		Read(id string) (data []byte, ok bool, err error)
+		//Write 写入会话数据, ttl 为 0 时由后端自行决定过期时间
+		Write(id string, data []byte, ttl time.Duration) error
+		Delete(id string) error
+	}
+
+	//serverStore cookie 中只保存会话 ID, 数据保存在 Backend 中
+	serverStore struct {
+		backend Backend
+		options Options
+	}
+)
+
+//ServerStore 创建服务端存储, options 为 nil 时使用 DefaultOptions
+func ServerStore(backend Backend, options *Options) Store {
+	if nil == options {
+		options = DefaultOptions()
+	}
+	return &serverStore{backend: backend, options: *options}
+}
+
+func (this *serverStore) Load(request *http.Request, name string) (*Session, error) {
+	session := NewSession(name, this.options)
+	cookie, err := request.Cookie(name)
+	if nil != err || cookie.Value == "" {
+		return session, nil
+	}
+	data, ok, err := this.backend.Read(cookie.Value)
+	if nil != err || !ok {
+		return session, err
+	}
+	values, err := decodeValues(data)
+	if nil != err {
+		return session, err
+	}
+	session.ID = cookie.Value
+	session.Values = values
+	session.isNew = false
+	return session, nil
+}
+
+func (this *serverStore) Save(writer http.ResponseWriter, request *http.Request, session *Session) error {
+	if session.previousID != "" {
+		if err := this.backend.Delete(session.previousID); nil != err {
+			return err
+		}
+		session.previousID = ""
+	}
+	if session.Options.MaxAge < 0 {
+		if !session.isNew {
+			if err := this.backend.Delete(session.ID); nil != err {
+				return err
+			}
+		}
+		http.SetCookie(writer, session.cookie(""))
+		return nil
+	}
+	data, err := encodeValues(session.Values)
+	if nil != err {
+		return err
+	}
+	ttl := time.Duration(session.Options.MaxAge) * time.Second
+	if err := this.backend.Write(session.ID, data, ttl); nil != err {
+		return err
+	}
+	http.SetCookie(writer, session.cookie(session.ID))
+	return nil
+}
@@ -1,3 +1,3 @@
 module dew
 
-go 1.13
+go 1.16
@@ -49,7 +49,7 @@ func onlyForV2() dew.HandlerFunction {
 		// Start timer
 		t := time.Now()
 		// if a server error occurred
-		context.WriteString(500, "", "Internal Server Error")
+		context.WriteString(500, "Internal Server Error")
 		// Calculate resolution time
 		log.Printf("[%d] %s in %v for group v2", context.Code, context.Request.RequestURI, time.Since(t))
 	}